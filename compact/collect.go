@@ -0,0 +1,70 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compact
+
+// NodeHash is a tree node ID paired with the node's hash.
+type NodeHash struct {
+	ID   NodeID
+	Hash []byte
+}
+
+// CollectingRange is a compact Range that records all the nodes that its
+// Append, AppendLeaf and AppendRange methods create, including the appended
+// leaves. This is convenient when building a tree for the first time, and
+// writing all its nodes to storage.
+type CollectingRange struct {
+	*Range
+	collected []NodeHash
+}
+
+// NewCollectingRange creates an empty CollectingRange with the given begin
+// index.
+func (f *RangeFactory) NewCollectingRange(begin uint64) *CollectingRange {
+	return &CollectingRange{Range: f.NewEmptyRange(begin)}
+}
+
+// Append works like Range.Append, and also collects the added nodes.
+func (r *CollectingRange) Append(hash []byte, visitor VisitFn) error {
+	return r.Range.Append(hash, r.visitor(visitor))
+}
+
+// AppendLeaf works like Range.AppendLeaf, and also collects the added nodes.
+func (r *CollectingRange) AppendLeaf(leaf []byte, visitor VisitFn) error {
+	return r.Range.AppendLeaf(leaf, r.visitor(visitor))
+}
+
+// AppendRange works like Range.AppendRange, and also collects the added nodes.
+// Note that the nodes of the other range are not collected, only the ones
+// created by merging.
+func (r *CollectingRange) AppendRange(other *Range, visitor VisitFn) error {
+	return r.Range.AppendRange(other, r.visitor(visitor))
+}
+
+// Collected returns all the collected nodes, in the order they were created.
+// The returned slice must not be modified.
+func (r *CollectingRange) Collected() []NodeHash {
+	return r.collected
+}
+
+// visitor returns a VisitFn that collects the node, and calls the passed in
+// visitor (if non-nil).
+func (r *CollectingRange) visitor(visitor VisitFn) VisitFn {
+	return func(id NodeID, hash []byte) {
+		r.collected = append(r.collected, NodeHash{ID: id, Hash: hash})
+		if visitor != nil {
+			visitor(id, hash)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compact_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/transparency-dev/merkle/compact"
+)
+
+func TestCollectingRange(t *testing.T) {
+	const size = uint64(37)
+	tree, _ := newTree(t, size)
+
+	var want []compact.NodeHash
+	visit := func(id compact.NodeID, hash []byte) {
+		want = append(want, compact.NodeHash{ID: id, Hash: hash})
+	}
+	var visited int
+	count := func(compact.NodeID, []byte) { visited++ }
+
+	rng := factory.NewEmptyRange(0)
+	cr := factory.NewCollectingRange(0)
+	for i := uint64(0); i < 20; i++ {
+		if err := rng.Append(tree.leaf(i), visit); err != nil {
+			t.Fatalf("Append(%d): %v", i, err)
+		}
+		if err := cr.Append(tree.leaf(i), count); err != nil {
+			t.Fatalf("Append(%d): %v", i, err)
+		}
+	}
+	other := newRangeOf(t, tree, 20, size)
+	if err := rng.AppendRange(other, visit); err != nil {
+		t.Fatalf("AppendRange: %v", err)
+	}
+	if err := cr.AppendRange(other, count); err != nil {
+		t.Fatalf("AppendRange: %v", err)
+	}
+
+	if diff := cmp.Diff(want, cr.Collected()); diff != "" {
+		t.Errorf("Collected: diff (-want +got)\n%s", diff)
+	}
+	if got, want := visited, len(want); got != want {
+		t.Errorf("visitor called %d times, want %d", got, want)
+	}
+	if !rng.Equal(cr.Range) {
+		t.Error("ranges differ")
+	}
+	// All collected nodes must be nodes of the tree.
+	for _, n := range cr.Collected() {
+		if got, want := n.Hash, tree.nodes[n.ID.Level][n.ID.Index].hash; !bytes.Equal(got, want) {
+			t.Errorf("node %+v: hash %x, want %x", n.ID, got, want)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compact
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// MultiRange holds a set of disjoint compact ranges, ordered by their begin
+// index. It can be used for accumulating ranges of a sharded log, and merging
+// them into a single range once they cover a contiguous interval of leaves.
+type MultiRange struct {
+	f      *RangeFactory
+	ranges []*Range
+}
+
+// NewMultiRange returns an empty MultiRange which accepts ranges created by
+// this factory.
+func (f *RangeFactory) NewMultiRange() *MultiRange {
+	return &MultiRange{f: f}
+}
+
+// Ranges returns the non-empty ranges added to this MultiRange, ordered by
+// their begin index.
+func (m *MultiRange) Ranges() []*Range {
+	return m.ranges
+}
+
+// Add inserts the given range into the set. Returns an error if the range was
+// created by a different factory, or if it overlaps with one of the ranges
+// already in the set. Empty ranges are ignored.
+func (m *MultiRange) Add(r *Range) error {
+	if r.f != m.f {
+		return errors.New("incompatible ranges")
+	}
+	if r.begin == r.end {
+		return nil
+	}
+	// Find the first range that ends after the new one begins.
+	i := sort.Search(len(m.ranges), func(i int) bool {
+		return m.ranges[i].end > r.begin
+	})
+	if i < len(m.ranges) && m.ranges[i].begin < r.end {
+		return fmt.Errorf("range [%d, %d) overlaps with [%d, %d)", r.begin, r.end, m.ranges[i].begin, m.ranges[i].end)
+	}
+	m.ranges = append(m.ranges, nil)
+	copy(m.ranges[i+1:], m.ranges[i:])
+	m.ranges[i] = r
+	return nil
+}
+
+// Merge returns a single compact range combining all the ranges in the set.
+// Returns an error if the set is empty, or if there is a gap between any two
+// consecutive ranges. The ranges in the set are not modified.
+func (m *MultiRange) Merge() (*Range, error) {
+	if len(m.ranges) == 0 {
+		return nil, errors.New("no ranges")
+	}
+	res := m.f.NewEmptyRange(m.ranges[0].begin)
+	for _, r := range m.ranges {
+		if r.begin != res.end {
+			return nil, fmt.Errorf("gap between ranges: [%d, %d)", res.end, r.begin)
+		}
+		if err := res.AppendRange(r, nil); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// GetRootHash returns the root hash of the Merkle tree of the given size.
+// Requires the ranges in the set to tile the [0, size) interval completely.
+func (m *MultiRange) GetRootHash(size uint64) ([]byte, error) {
+	if size == 0 {
+		return nil, errors.New("empty tree")
+	}
+	r, err := m.Merge()
+	if err != nil {
+		return nil, err
+	}
+	if r.begin != 0 || r.end != size {
+		return nil, fmt.Errorf("ranges cover [%d, %d), want [0, %d)", r.begin, r.end, size)
+	}
+	return r.GetRootHash(nil)
+}
@@ -0,0 +1,102 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package compact_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/transparency-dev/merkle/compact"
+)
+
+// newRangeOf returns a compact range [begin, end) of the given tree.
+func newRangeOf(t *testing.T, tr *tree, begin, end uint64) *compact.Range {
+	t.Helper()
+	rng := factory.NewEmptyRange(begin)
+	for i := begin; i < end; i++ {
+		if err := rng.Append(tr.leaf(i), nil); err != nil {
+			t.Fatalf("Append(%d): %v", i, err)
+		}
+	}
+	return rng
+}
+
+func TestMultiRange(t *testing.T) {
+	const size = uint64(16)
+	tree, _ := newTree(t, size)
+	root := tree.rootHash()
+
+	for _, tc := range []struct {
+		desc    string
+		bounds  [][2]uint64
+		wantErr bool
+	}{
+		{desc: "four-ranges", bounds: [][2]uint64{{0, 3}, {3, 8}, {8, 13}, {13, 16}}},
+		{desc: "unordered", bounds: [][2]uint64{{8, 13}, {0, 3}, {13, 16}, {3, 8}}},
+		{desc: "with-empty", bounds: [][2]uint64{{0, 5}, {5, 5}, {5, 16}}},
+		{desc: "single", bounds: [][2]uint64{{0, 16}}},
+		{desc: "gap", bounds: [][2]uint64{{0, 3}, {3, 8}, {9, 13}, {13, 16}}, wantErr: true},
+		{desc: "no-prefix", bounds: [][2]uint64{{3, 8}, {8, 16}}, wantErr: true},
+		{desc: "no-suffix", bounds: [][2]uint64{{0, 8}, {8, 15}}, wantErr: true},
+		{desc: "empty", wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			mr := factory.NewMultiRange()
+			for _, b := range tc.bounds {
+				if err := mr.Add(newRangeOf(t, tree, b[0], b[1])); err != nil {
+					t.Fatalf("Add(%d, %d): %v", b[0], b[1], err)
+				}
+			}
+			got, err := mr.GetRootHash(size)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("GetRootHash succeeded unexpectedly")
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("GetRootHash: %v", err)
+			}
+			if !bytes.Equal(got, root) {
+				t.Errorf("GetRootHash: got %08x, want %08x", shorten(got), shorten(root))
+			}
+		})
+	}
+}
+
+func TestMultiRangeAddErrors(t *testing.T) {
+	tree, _ := newTree(t, 16)
+	anotherFactory := &compact.RangeFactory{Hash: factory.Hash}
+
+	mr := factory.NewMultiRange()
+	for _, b := range [][2]uint64{{2, 5}, {8, 12}} {
+		if err := mr.Add(newRangeOf(t, tree, b[0], b[1])); err != nil {
+			t.Fatalf("Add(%d, %d): %v", b[0], b[1], err)
+		}
+	}
+	if err := mr.Add(anotherFactory.NewEmptyRange(0)); err == nil {
+		t.Error("Add accepted a range of another factory")
+	}
+	for _, b := range [][2]uint64{{0, 3}, {4, 9}, {2, 5}, {11, 16}, {0, 16}} {
+		t.Run(fmt.Sprintf("%d:%d", b[0], b[1]), func(t *testing.T) {
+			if err := mr.Add(newRangeOf(t, tree, b[0], b[1])); err == nil {
+				t.Error("Add accepted an overlapping range")
+			}
+		})
+	}
+	if got, want := len(mr.Ranges()), 2; got != want {
+		t.Errorf("Ranges: got %d ranges, want %d", got, want)
+	}
+}
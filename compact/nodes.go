@@ -14,7 +14,10 @@
 
 package compact
 
-import "math/bits"
+import (
+	"math/bits"
+	"slices"
+)
 
 // NodeID identifies a node of a Merkle tree.
 //
@@ -51,15 +54,130 @@ func (id NodeID) Sibling() NodeID {
 	return NewNodeID(id.Level, id.Index^1)
 }
 
-// Coverage returns the [begin, end) range of leaves covered by the node.
+// Less reports whether this node ID is ordered before the other one. The IDs
+// are ordered by level, and then by index within the level.
+func (id NodeID) Less(other NodeID) bool {
+	if id.Level != other.Level {
+		return id.Level < other.Level
+	}
+	return id.Index < other.Index
+}
+
+// NodeMap maps node IDs to their hashes. Unlike a plain map, it supports
+// iterating over the nodes in a deterministic order.
+type NodeMap map[NodeID][]byte
+
+// Range calls fn for each node in the map, in the order of NodeID.Less.
+func (m NodeMap) Range(fn func(id NodeID, hash []byte)) {
+	ids := make([]NodeID, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b NodeID) int {
+		if a.Less(b) {
+			return -1
+		} else if b.Less(a) {
+			return 1
+		}
+		return 0
+	})
+	for _, id := range ids {
+		fn(id, m[id])
+	}
+}
+
+// Interval represents the [Begin, End) range of leaf indices.
+type Interval struct {
+	Begin uint64 // The first leaf index, inclusive.
+	End   uint64 // The last leaf index, exclusive.
+}
+
+// Coverage returns the [begin, end) range of leaves covered by the node. The
+// node at the given level and index covers 2^level leaves, i.e. the range
+// [index * 2^level, (index+1) * 2^level). For example, the leaf (level 0) with
+// index 5 covers [5, 6), and the node at level 2 with index 1 covers [4, 8).
+//
+// The end of the last node at a level, which covers the 2^64-th leaf index,
+// overflows and is returned as 0.
 func (id NodeID) Coverage() (uint64, uint64) {
 	return id.Index << id.Level, (id.Index + 1) << id.Level
 }
 
+// ContainingSubtree returns the ID of the largest perfect subtree which
+// contains the given leaf index, and is complete in the tree of the given
+// size. This node is one of the nodes of the [0, size) compact range.
+//
+// The output is not specified if index >= size, but the function never panics.
+func ContainingSubtree(index, size uint64) NodeID {
+	// The paths from the root to leaves #index and #size diverge at this level.
+	level := uint(bits.Len64(index^size)) - 1
+	return NewNodeID(level, index>>level)
+}
+
+// SiblingPath returns the IDs of the siblings of all nodes on the path from
+// the given leaf up to its containing subtree (see ContainingSubtree), ordered
+// from lower levels to upper. This is the first portion of the inclusion proof
+// for the leaf in the tree of the given size, which can be used for
+// prefetching. Returns nil if index >= size.
+func SiblingPath(index, size uint64) []NodeID {
+	if index >= size {
+		return nil
+	}
+	root := ContainingSubtree(index, size)
+	ids := make([]NodeID, 0, root.Level)
+	for id := NewNodeID(0, index); id.Level < root.Level; id = id.Parent() {
+		ids = append(ids, id.Sibling())
+	}
+	return ids
+}
+
 // RangeNodes appends the IDs of the nodes that comprise the [begin, end)
 // compact range to the given slice, and returns the new slice. The caller may
 // pre-allocate space with the help of the RangeSize function.
 func RangeNodes(begin, end uint64, ids []NodeID) []NodeID {
+	if begin == 0 {
+		return prefixNodes(end, ids)
+	}
+	return rangeNodes(begin, end, ids)
+}
+
+// ConsistencyAsRangeDelta describes the consistency proof between tree sizes
+// size1 and size2 in terms of compact ranges. The tree of size2 is the merge of
+// the [0, size1) prefix compact range and the [size1, size2) delta compact
+// range. The nodes of the consistency proof are exactly the nodes of these two
+// ranges, except that the ephemeral part of the delta is rehashed into a single
+// node. If size1 is a power of 2, the prefix range is the single node whose
+// hash is the root hash of size1, which the verifier already knows, so it is
+// not part of the proof and the returned prefix is nil.
+//
+// Returns nil slices if the proof is empty, i.e. size1 == size2, or if no proof
+// exists, i.e. size1 == 0 or size1 > size2.
+func ConsistencyAsRangeDelta(size1, size2 uint64) (prefix, delta []NodeID) {
+	if size1 == 0 || size1 >= size2 {
+		return nil, nil
+	}
+	if size1&(size1-1) != 0 {
+		prefix = RangeNodes(0, size1, nil)
+	}
+	return prefix, RangeNodes(size1, size2, nil)
+}
+
+// prefixNodes is a special case of RangeNodes for the [0, size) range, i.e. the
+// perfect subtrees of the tree of the given size. Each one bit of size at a
+// given level corresponds to the node at this level, ordered from upper levels
+// to lower, and the index of this node is (size >> level) - 1.
+func prefixNodes(size uint64, ids []NodeID) []NodeID {
+	ids = slices.Grow(ids, bits.OnesCount64(size))
+	for rem := size; rem != 0; {
+		level := uint(bits.Len64(rem)) - 1
+		ids = append(ids, NewNodeID(level, size>>level-1))
+		rem ^= uint64(1) << level
+	}
+	return ids
+}
+
+// rangeNodes implements RangeNodes for an arbitrary range.
+func rangeNodes(begin, end uint64, ids []NodeID) []NodeID {
 	left, right := Decompose(begin, end)
 
 	pos := begin
@@ -82,6 +200,27 @@ func RangeNodes(begin, end uint64, ids []NodeID) []NodeID {
 	return ids
 }
 
+// Fringe returns, for each level of the tree of the given size, the trailing
+// leaves that are not covered by a perfect subtree at this level, i.e. whose
+// node at this level is not yet complete. The slice is indexed by level, from
+// 0 up to the level of the tree root. Some intervals may be empty, e.g. at
+// levels where the size is a multiple of the subtree size. Returns nil if size
+// is 0.
+//
+// For example, for size 7 the fringe is [7, 7) at level 0, [6, 7) at level 1
+// (the incomplete node (1, 3)), [4, 7) at level 2, and [0, 7) at level 3.
+func Fringe(size uint64) []Interval {
+	if size == 0 {
+		return nil
+	}
+	levels := bits.Len64(size - 1)
+	res := make([]Interval, levels+1)
+	for level := range res {
+		res[level] = Interval{Begin: size >> level << level, End: size}
+	}
+	return res
+}
+
 // RangeSize returns the number of nodes in the [begin, end) compact range.
 func RangeSize(begin, end uint64) int {
 	left, right := Decompose(begin, end)
@@ -104,6 +104,185 @@ func TestGenRangeNodes(t *testing.T) {
 	}
 }
 
+func TestPrefixNodes(t *testing.T) {
+	prefix := []NodeID{NewNodeID(0, 0)}
+	for _, size := range []uint64{0, 1, 2, 7, 1 << 40, 1<<40 - 1, 1<<63 + 5, 1<<64 - 1} {
+		got := prefixNodes(size, prefix[:1:1])
+		want := rangeNodes(0, size, prefix[:1:1])
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("prefixNodes(%d): diff(-want +got):\n%s", size, diff)
+		}
+	}
+}
+
+func TestNodeIDLess(t *testing.T) {
+	n := NewNodeID
+	for _, tc := range []struct {
+		a, b NodeID
+		want bool
+	}{
+		{a: n(0, 0), b: n(0, 0), want: false},
+		{a: n(0, 0), b: n(0, 1), want: true},
+		{a: n(0, 1), b: n(0, 0), want: false},
+		{a: n(0, 100), b: n(1, 0), want: true},
+		{a: n(1, 0), b: n(0, 100), want: false},
+		{a: n(2, 5), b: n(2, 7), want: true},
+	} {
+		if got := tc.a.Less(tc.b); got != tc.want {
+			t.Errorf("%+v.Less(%+v): got %v, want %v", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestNodeMapRange(t *testing.T) {
+	m := make(NodeMap)
+	for _, id := range RangeNodes(3, 1000, nil) {
+		m[id] = []byte(fmt.Sprintf("%d:%d", id.Level, id.Index))
+	}
+	var ids []NodeID
+	m.Range(func(id NodeID, hash []byte) {
+		if got, want := string(hash), fmt.Sprintf("%d:%d", id.Level, id.Index); got != want {
+			t.Errorf("Range: node %+v has hash %q, want %q", id, got, want)
+		}
+		ids = append(ids, id)
+	})
+	if got, want := len(ids), len(m); got != want {
+		t.Fatalf("Range: visited %d nodes, want %d", got, want)
+	}
+	for i := 1; i < len(ids); i++ {
+		if !ids[i-1].Less(ids[i]) {
+			t.Errorf("Range: node %+v visited before %+v", ids[i-1], ids[i])
+		}
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	for _, tc := range []struct {
+		id         NodeID
+		begin, end uint64
+	}{
+		{id: NewNodeID(0, 0), begin: 0, end: 1},
+		{id: NewNodeID(0, 5), begin: 5, end: 6},
+		{id: NewNodeID(1, 0), begin: 0, end: 2},
+		{id: NewNodeID(1, 3), begin: 6, end: 8},
+		{id: NewNodeID(2, 1), begin: 4, end: 8},
+		{id: NewNodeID(3, 2), begin: 16, end: 24},
+		{id: NewNodeID(4, 3), begin: 48, end: 64},
+		// The largest indices at each level. The end overflows to 0.
+		{id: NewNodeID(0, 1<<64-1), begin: 1<<64 - 1, end: 0},
+		{id: NewNodeID(1, 1<<63-1), begin: 1<<64 - 2, end: 0},
+		{id: NewNodeID(2, 1<<62-1), begin: 1<<64 - 4, end: 0},
+		{id: NewNodeID(3, 1<<61-1), begin: 1<<64 - 8, end: 0},
+		{id: NewNodeID(4, 1<<60-1), begin: 1<<64 - 16, end: 0},
+		{id: NewNodeID(4, 1<<60-2), begin: 1<<64 - 32, end: 1<<64 - 16},
+		{id: NewNodeID(63, 1), begin: 1 << 63, end: 0},
+	} {
+		t.Run(fmt.Sprintf("%d:%d", tc.id.Level, tc.id.Index), func(t *testing.T) {
+			begin, end := tc.id.Coverage()
+			if begin != tc.begin || end != tc.end {
+				t.Errorf("Coverage: got [%d, %d), want [%d, %d)", begin, end, tc.begin, tc.end)
+			}
+		})
+	}
+}
+
+func TestFringe(t *testing.T) {
+	for _, tc := range []struct {
+		size uint64
+		want []Interval
+	}{
+		{size: 0, want: nil},
+		{size: 1, want: []Interval{{1, 1}}},
+		{size: 2, want: []Interval{{2, 2}, {2, 2}}},
+		{size: 7, want: []Interval{{7, 7}, {6, 7}, {4, 7}, {0, 7}}},
+		{size: 8, want: []Interval{{8, 8}, {8, 8}, {8, 8}, {8, 8}}},
+		{size: 9, want: []Interval{{9, 9}, {8, 9}, {8, 9}, {8, 9}, {0, 9}}},
+		{size: 12, want: []Interval{{12, 12}, {12, 12}, {12, 12}, {8, 12}, {0, 12}}},
+	} {
+		t.Run(fmt.Sprintf("%d", tc.size), func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, Fringe(tc.size)); diff != "" {
+				t.Errorf("Fringe: diff (-want +got)\n%s", diff)
+			}
+		})
+	}
+
+	// The fringe at the root level is the whole tree.
+	for _, size := range []uint64{3, 100, 1<<40 + 5, 1<<64 - 1} {
+		fringe := Fringe(size)
+		if got, want := fringe[len(fringe)-1], (Interval{0, size}); got != want {
+			t.Errorf("Fringe(%d) at root: got %v, want %v", size, got, want)
+		}
+	}
+}
+
+func TestContainingSubtree(t *testing.T) {
+	for _, tc := range []struct {
+		index, size uint64
+		want        NodeID
+	}{
+		{index: 0, size: 1, want: NewNodeID(0, 0)},
+		{index: 0, size: 2, want: NewNodeID(1, 0)},
+		{index: 2, size: 3, want: NewNodeID(0, 2)},
+		{index: 0, size: 7, want: NewNodeID(2, 0)},
+		{index: 3, size: 7, want: NewNodeID(2, 0)},
+		{index: 4, size: 7, want: NewNodeID(1, 2)},
+		{index: 5, size: 7, want: NewNodeID(1, 2)},
+		{index: 6, size: 7, want: NewNodeID(0, 6)},
+		{index: 7, size: 8, want: NewNodeID(3, 0)},
+		{index: 99, size: 100, want: NewNodeID(2, 24)},
+		{index: 1024, size: 1025, want: NewNodeID(0, 1024)},
+		{index: 1023, size: 1025, want: NewNodeID(10, 0)},
+		{index: 1<<63 - 1, size: 1 << 63, want: NewNodeID(63, 0)},
+		{index: 1<<64 - 2, size: 1<<64 - 1, want: NewNodeID(0, 1<<64-2)},
+	} {
+		t.Run(fmt.Sprintf("%d:%d", tc.index, tc.size), func(t *testing.T) {
+			if got, want := ContainingSubtree(tc.index, tc.size), tc.want; got != want {
+				t.Errorf("ContainingSubtree: got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestSiblingPath(t *testing.T) {
+	n := NewNodeID
+	for _, tc := range []struct {
+		index, size uint64
+		want        []NodeID
+	}{
+		{index: 0, size: 0, want: nil},
+		{index: 7, size: 7, want: nil},
+		{index: 0, size: 1, want: []NodeID{}},
+		{index: 6, size: 7, want: []NodeID{}},
+		{index: 2, size: 7, want: []NodeID{n(0, 3), n(1, 0)}},
+		{index: 5, size: 7, want: []NodeID{n(0, 4)}},
+		{index: 5, size: 8, want: []NodeID{n(0, 4), n(1, 3), n(2, 0)}},
+	} {
+		t.Run(fmt.Sprintf("%d:%d", tc.index, tc.size), func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, SiblingPath(tc.index, tc.size)); diff != "" {
+				t.Errorf("SiblingPath: diff(-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestContainingSubtreeIsRangeNode(t *testing.T) {
+	for size := uint64(1); size <= 256; size++ {
+		ids := RangeNodes(0, size, nil)
+		for index := uint64(0); index < size; index++ {
+			got := ContainingSubtree(index, size)
+			var want NodeID
+			for _, id := range ids {
+				if begin, end := id.Coverage(); index >= begin && index < end {
+					want = id
+				}
+			}
+			if got != want {
+				t.Fatalf("ContainingSubtree(%d, %d): got %+v, want %+v", index, size, got, want)
+			}
+		}
+	}
+}
+
 // refRangeNodes returns node IDs that comprise the [begin, end) compact range.
 // This is a reference implementation for cross-checking.
 func refRangeNodes(root NodeID, begin, end uint64) []NodeID {
@@ -118,3 +297,18 @@ func refRangeNodes(root NodeID, begin, end uint64) []NodeID {
 		refRangeNodes(NewNodeID(root.Level-1, root.Index*2), begin, end),
 		refRangeNodes(NewNodeID(root.Level-1, root.Index*2+1), begin, end)...)
 }
+
+func BenchmarkRangeNodes(b *testing.B) {
+	for _, size := range []uint64{1000, 1<<20 - 1, 1<<40 - 1} {
+		b.Run(fmt.Sprintf("prefix:%d", size), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				_ = prefixNodes(size, nil)
+			}
+		})
+		b.Run(fmt.Sprintf("general:%d", size), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				_ = rangeNodes(0, size, nil)
+			}
+		})
+	}
+}
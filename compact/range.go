@@ -25,6 +25,9 @@ import (
 // HashFn computes an internal node's hash using the hashes of its child nodes.
 type HashFn func(left, right []byte) []byte
 
+// LeafHashFn computes a leaf's hash using the leaf data.
+type LeafHashFn func(leaf []byte) []byte
+
 // VisitFn visits the node with the specified ID and hash.
 type VisitFn func(id NodeID, hash []byte)
 
@@ -32,6 +35,8 @@ type VisitFn func(id NodeID, hash []byte)
 // function, which must not be nil, and must not be changed.
 type RangeFactory struct {
 	Hash HashFn
+	// LeafHash is an optional leaf hash function, used by Range.AppendLeaf.
+	LeafHash LeafHashFn
 }
 
 // NewRange creates a Range for [begin, end) with the given set of hashes. The
@@ -47,6 +52,48 @@ func (f *RangeFactory) NewRange(begin, end uint64, hashes [][]byte) (*Range, err
 	return &Range{f: f, begin: begin, end: end, hashes: hashes}, nil
 }
 
+// NewRangeFromNodes creates a Range for [begin, end) like NewRange, but takes
+// the hashes of the nodes listed by RangeNodes(begin, end) from the given map.
+// Returns an error if any of these nodes is missing. Other nodes in the map are
+// ignored.
+func (f *RangeFactory) NewRangeFromNodes(begin, end uint64, nodes NodeMap) (*Range, error) {
+	if end < begin {
+		return nil, fmt.Errorf("invalid range: end=%d, want >= %d", end, begin)
+	}
+	ids := RangeNodes(begin, end, nil)
+	hashes := make([][]byte, len(ids))
+	for i, id := range ids {
+		hash, ok := nodes[id]
+		if !ok {
+			return nil, fmt.Errorf("node %+v not found", id)
+		}
+		hashes[i] = hash
+	}
+	return f.NewRange(begin, end, hashes)
+}
+
+// NewRangeVerified creates a Range for [0, end) like NewRange, and checks that
+// its root hash matches the expected one. This is useful for loading hashes
+// received from an untrusted source. Only ranges starting at index 0 can be
+// verified this way, so begin must be 0.
+func (f *RangeFactory) NewRangeVerified(begin, end uint64, hashes [][]byte, expectedRoot []byte) (*Range, error) {
+	if begin != 0 {
+		return nil, fmt.Errorf("begin=%d, want 0", begin)
+	}
+	r, err := f.NewRange(begin, end, hashes)
+	if err != nil {
+		return nil, err
+	}
+	root, err := r.GetRootHash(nil)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(root, expectedRoot) {
+		return nil, fmt.Errorf("root hash mismatch: got %x, want %x", root, expectedRoot)
+	}
+	return r, nil
+}
+
 // NewEmptyRange returns a new Range for an empty [begin, begin) range. The
 // value of begin defines where the range will start growing from when entries
 // are appended to it.
@@ -85,6 +132,13 @@ func (r *Range) Hashes() [][]byte {
 	return r.hashes
 }
 
+// Spine returns the IDs of the nodes whose hashes are returned by Hashes, in
+// the same order. Persisting these IDs alongside the hashes allows serving
+// proofs involving these nodes later.
+func (r *Range) Spine() []NodeID {
+	return RangeNodes(r.begin, r.end, make([]NodeID, 0, len(r.hashes)))
+}
+
 // Append extends the compact range by appending the passed in hash to it. It
 // reports all the added nodes through the visitor function (if non-nil).
 func (r *Range) Append(hash []byte, visitor VisitFn) error {
@@ -94,20 +148,52 @@ func (r *Range) Append(hash []byte, visitor VisitFn) error {
 	return r.appendImpl(r.end+1, hash, nil, visitor)
 }
 
+// AppendLeaf extends the compact range by appending the hash of the passed in
+// leaf data to it, computed with the factory's LeafHash function. It reports
+// all the added nodes through the visitor function (if non-nil).
+func (r *Range) AppendLeaf(leaf []byte, visitor VisitFn) error {
+	if r.f.LeafHash == nil {
+		return errors.New("leaf hash function not set")
+	}
+	return r.Append(r.f.LeafHash(leaf), visitor)
+}
+
 // AppendRange extends the compact range by merging in the other compact range
 // from the right. It uses the tree hasher to calculate hashes of newly created
 // nodes, and reports them through the visitor function (if non-nil).
 func (r *Range) AppendRange(other *Range, visitor VisitFn) error {
+	if err := r.checkAppendRange(other); err != nil {
+		return err
+	}
+	if len(other.hashes) == 0 { // The other range is empty, merging is trivial.
+		return nil
+	}
+	return r.appendImpl(other.end, other.hashes[0], other.hashes[1:], visitor)
+}
+
+// CanAppend checks whether the other compact range can be merged into this
+// one with AppendRange. It returns the error that AppendRange would return, but
+// does not compute any hashes or modify the ranges. This can be used to
+// validate a batch of ranges before merging them.
+func (r *Range) CanAppend(other *Range) error {
+	if err := r.checkAppendRange(other); err != nil {
+		return err
+	}
+	if len(other.hashes) == 0 {
+		return nil
+	}
+	_, _, err := r.checkMerge(other.end, len(other.hashes)-1)
+	return err
+}
+
+func (r *Range) checkAppendRange(other *Range) error {
 	if other.f != r.f {
 		return errors.New("incompatible ranges")
 	}
 	if got, want := other.begin, r.end; got != want {
 		return fmt.Errorf("ranges are disjoint: other.begin=%d, want %d", got, want)
 	}
-	if len(other.hashes) == 0 { // The other range is empty, merging is trivial.
-		return nil
-	}
-	return r.appendImpl(other.end, other.hashes[0], other.hashes[1:], visitor)
+	return nil
 }
 
 // GetRootHash returns the root hash of the Merkle tree represented by this
@@ -140,6 +226,111 @@ func (r *Range) GetRootHash(visitor VisitFn) ([]byte, error) {
 	return hash, nil
 }
 
+// RootAt returns the root hash of the tree of the given size2, which is a
+// prefix of the tree represented by this compact range. Requires the range to
+// start at index 0, and size2 <= End(). The root hash can only be derived if
+// the [0, size2) compact range is a prefix of this one, i.e. if size2 is End()
+// with some of its lowest bits cleared. Otherwise an error is returned. If
+// size2 is 0, returns nil.
+//
+// For example, the range [0, 14) can derive the roots at sizes 14, 12 and 8,
+// but the range [0, 8) can not derive the root at size 5.
+func (r *Range) RootAt(size2 uint64) ([]byte, error) {
+	if r.begin != 0 {
+		return nil, fmt.Errorf("begin=%d, want 0", r.begin)
+	} else if size2 > r.end {
+		return nil, fmt.Errorf("size %d beyond range end %d", size2, r.end)
+	}
+	// The [0, size2) range is a prefix of [0, end) iff size2 is end with some
+	// lowest bits cleared, i.e. size2 matches end on all bits above its lowest.
+	count := bits.OnesCount64(size2)
+	if size2 != 0 {
+		if low := size2 & -size2; r.end&^(low-1) != size2 {
+			return nil, fmt.Errorf("root at size %d can not be derived from range [0, %d)", size2, r.end)
+		}
+	}
+	prefix := &Range{f: r.f, begin: 0, end: size2, hashes: r.hashes[:count:count]}
+	return prefix.GetRootHash(nil)
+}
+
+// InclusionProofLen returns the number of hashes in the inclusion proof for
+// the leaf with the given index in the tree of size End(). Returns an error if
+// the index is not below End().
+func (r *Range) InclusionProofLen(index uint64) (int, error) {
+	if index >= r.end {
+		return 0, fmt.Errorf("index %d out of bounds for tree size %d", index, r.end)
+	}
+	// The proof consists of the siblings of the path nodes below the point where
+	// the paths to the leaf and to the last leaf diverge, plus the nodes of the
+	// compact range to the left of the leaf's containing subtree.
+	inner := bits.Len64(index ^ (r.end - 1))
+	return inner + bits.OnesCount64(index>>inner), nil
+}
+
+// PathHashes walks the path from the given leaf up to the root, and calls
+// yield with the ID and hash of each node on this path that can be computed
+// from this range, from lower levels to upper. It stops early if yield returns
+// false. Does nothing if the leaf is not in the range.
+//
+// The first reported node is the node of the range containing the leaf. The
+// nodes above it cover leaves beyond the range end, so they are reported with
+// "ephemeral" hashes, i.e. the hashes of the subtrees truncated at the range
+// end, like in GetRootHash. The levels at which the ephemeral hash does not
+// change are skipped. The walk ends at the lowest node that covers leaves
+// before the range begin. For a range that begins at 0, the last reported hash
+// is the root hash.
+//
+// Only the hashes that are computed are held in memory, so this can be used
+// for streaming proof generation in large trees.
+func (r *Range) PathHashes(index uint64, yield func(NodeID, []byte) bool) {
+	if index < r.begin || index >= r.end {
+		return
+	}
+	ids := r.Spine()
+	pos := 0
+	for ; pos < len(ids); pos++ {
+		if _, end := ids[pos].Coverage(); index < end {
+			break
+		}
+	}
+	id, hash := ids[pos], r.hashes[pos]
+	if !yield(id, hash) {
+		return
+	}
+	// parentCovers returns whether the parent of the given node covers all the
+	// nodes to the right of it up to the range end.
+	parentCovers := func(id NodeID) bool {
+		_, end := id.Parent().Coverage()
+		return id.Index&1 == 0 && (end == 0 || end >= r.end)
+	}
+
+	// Merge in all the nodes to the right of the containing one.
+	if last := len(ids) - 1; pos < last {
+		if !parentCovers(id) {
+			return
+		}
+		right := r.hashes[last]
+		for i := last - 1; i > pos; i-- {
+			right = r.f.Hash(r.hashes[i], right)
+		}
+		id, hash = id.Parent(), r.f.Hash(hash, right)
+		if !yield(id, hash) {
+			return
+		}
+	}
+	// Merge in the nodes to the left, one at a time.
+	for i := pos - 1; i >= 0; i-- {
+		left := ids[i]
+		if !parentCovers(left) || left.Parent().Level <= id.Level {
+			return
+		}
+		id, hash = left.Parent(), r.f.Hash(r.hashes[i], hash)
+		if !yield(id, hash) {
+			return
+		}
+	}
+}
+
 // Equal compares two Ranges for equality.
 func (r *Range) Equal(other *Range) bool {
 	if r.f != other.f || r.begin != other.begin || r.end != other.end {
@@ -156,33 +347,50 @@ func (r *Range) Equal(other *Range) bool {
 	return true
 }
 
+// Extends reports whether the other compact range is an extension of this
+// one, i.e. this range is its prefix. Both ranges must begin at index 0, and
+// this range must not be longer than the other one. The check compares the
+// root hash of this range with the root hash of the other range at this
+// range's size, as derived by other.RootAt.
+//
+// Note that a compact range does not always contain enough information to
+// derive its root hash at an earlier size, see RootAt. In this case an error is
+// returned, and a consistency proof is needed to check that one range is a
+// prefix of the other.
+func (r *Range) Extends(other *Range) (bool, error) {
+	if other.f != r.f {
+		return false, errors.New("incompatible ranges")
+	}
+	if r.begin != 0 || other.begin != 0 {
+		return false, fmt.Errorf("ranges begin at %d and %d, want 0", r.begin, other.begin)
+	}
+	if r.end > other.end {
+		return false, fmt.Errorf("other range is shorter: %d < %d", other.end, r.end)
+	}
+	want, err := r.GetRootHash(nil)
+	if err != nil {
+		return false, err
+	}
+	got, err := other.RootAt(r.end)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(got, want), nil
+}
+
 // appendImpl extends the compact range by merging the [r.end, end) compact
 // range into it. The other compact range is decomposed into a seed hash and
 // all the other hashes (possibly none). The method uses the tree hasher to
 // calculate hashes of newly created nodes, and reports them through the
 // visitor function (if non-nil).
 func (r *Range) appendImpl(end uint64, seed []byte, hashes [][]byte, visitor VisitFn) error {
-	// Bits [low, high) of r.end encode the merge path, i.e. the sequence of node
-	// merges that transforms the two compact ranges into one.
-	low, high := getMergePath(r.begin, r.end, end)
-	if high < low {
-		high = low
+	low, high, err := r.checkMerge(end, len(hashes))
+	if err != nil {
+		return err
 	}
 	index := r.end >> low
 	// Now bits [0, high-low) of index encode the merge path.
 
-	// The number of one bits in index is the number of nodes from the left range
-	// that will be merged, and zero bits correspond to the nodes in the right
-	// range. Below we make sure that both ranges have enough hashes, which can
-	// be false only in case the data is corrupted in some way.
-	ones := bits.OnesCount64(index & (1<<(high-low) - 1))
-	if ln := len(r.hashes); ln < ones {
-		return fmt.Errorf("corrupted lhs range: got %d hashes, want >= %d", ln, ones)
-	}
-	if ln, zeros := len(hashes), int(high-low)-ones; ln < zeros {
-		return fmt.Errorf("corrupted rhs range: got %d hashes, want >= %d", ln+1, zeros+1)
-	}
-
 	// Some of the trailing nodes of the left compact range, and some of the
 	// leading nodes of the right range, are sequentially merged with the seed,
 	// according to the mask. All new nodes are reported through the visitor.
@@ -208,6 +416,33 @@ func (r *Range) appendImpl(end uint64, seed []byte, hashes [][]byte, visitor Vis
 	return nil
 }
 
+// checkMerge returns the bits [low, high) of r.end which encode the merge
+// path, i.e. the sequence of node merges that transforms this compact range and
+// the [r.end, end) range into one. The latter is represented by a seed hash
+// and the given number of other hashes. Returns an error if the ranges don't
+// have enough hashes for the merge.
+func (r *Range) checkMerge(end uint64, hashes int) (uint, uint, error) {
+	low, high := getMergePath(r.begin, r.end, end)
+	if high < low {
+		high = low
+	}
+	index := r.end >> low
+	// Now bits [0, high-low) of index encode the merge path.
+
+	// The number of one bits in index is the number of nodes from the left range
+	// that will be merged, and zero bits correspond to the nodes in the right
+	// range. Below we make sure that both ranges have enough hashes, which can
+	// be false only in case the data is corrupted in some way.
+	ones := bits.OnesCount64(index & (1<<(high-low) - 1))
+	if ln := len(r.hashes); ln < ones {
+		return 0, 0, fmt.Errorf("corrupted lhs range: got %d hashes, want >= %d", ln, ones)
+	}
+	if zeros := int(high-low) - ones; hashes < zeros {
+		return 0, 0, fmt.Errorf("corrupted rhs range: got %d hashes, want >= %d", hashes+1, zeros+1)
+	}
+	return low, high, nil
+}
+
 // getMergePath returns the merging path between the compact range [begin, mid)
 // and [mid, end). The path is represented as a range of bits within mid, with
 // bit indices [low, high). A bit value of 1 on level i of mid means that the
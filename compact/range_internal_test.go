@@ -83,6 +83,79 @@ func TestAppendRangeErrors(t *testing.T) {
 	}
 }
 
+func TestCanAppend(t *testing.T) {
+	anotherFactory := &RangeFactory{Hash: factory.Hash}
+	corrupt := func(rng *Range, dBegin, dEnd int64) *Range {
+		rng.begin = uint64(int64(rng.begin) + dBegin)
+		rng.end = uint64(int64(rng.end) + dEnd)
+		return rng
+	}
+	for _, tc := range []struct {
+		desc    string
+		l, r    *Range
+		wantErr string
+	}{
+		{
+			desc: "ok",
+			l:    factory.NewEmptyRange(0),
+			r:    factory.NewEmptyRange(0),
+		},
+		{
+			desc: "ok_non_empty",
+			l:    mustNewRange(t, 0, 6, [][]byte{[]byte("hash0"), []byte("hash1")}),
+			r:    mustNewRange(t, 6, 7, [][]byte{[]byte("hash")}),
+		},
+		{
+			desc:    "incompatible",
+			l:       factory.NewEmptyRange(0),
+			r:       anotherFactory.NewEmptyRange(0),
+			wantErr: "incompatible ranges",
+		},
+		{
+			desc:    "disjoint",
+			l:       factory.NewEmptyRange(0),
+			r:       factory.NewEmptyRange(1),
+			wantErr: "ranges are disjoint",
+		},
+		{
+			desc:    "left_corrupted",
+			l:       corrupt(factory.NewEmptyRange(7), -7, 0),
+			r:       mustNewRange(t, 7, 8, [][]byte{[]byte("hash")}),
+			wantErr: "corrupted lhs range",
+		},
+		{
+			desc:    "right_corrupted",
+			l:       mustNewRange(t, 0, 6, [][]byte{[]byte("hash0"), []byte("hash1")}),
+			r:       corrupt(mustNewRange(t, 6, 7, [][]byte{[]byte("hash")}), 0, 20),
+			wantErr: "corrupted rhs range",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			l := &Range{f: tc.l.f, begin: tc.l.begin, end: tc.l.end, hashes: append([][]byte{}, tc.l.hashes...)}
+			err := tc.l.CanAppend(tc.r)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("CanAppend: %v; want nil", err)
+				}
+			} else if err == nil || !strings.HasPrefix(err.Error(), tc.wantErr) {
+				t.Fatalf("CanAppend: %v; want containing %q", err, tc.wantErr)
+			}
+			if !tc.l.Equal(l) {
+				t.Fatal("CanAppend modified the range")
+			}
+		})
+	}
+}
+
+func mustNewRange(t *testing.T, begin, end uint64, hashes [][]byte) *Range {
+	t.Helper()
+	rng, err := factory.NewRange(begin, end, hashes)
+	if err != nil {
+		t.Fatalf("NewRange: %v", err)
+	}
+	return rng
+}
+
 func TestEqual(t *testing.T) {
 	for _, test := range []struct {
 		desc      string
@@ -22,10 +22,13 @@ import (
 	"math/bits"
 	"math/rand/v2"
 	"reflect"
+	"slices"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/proof"
 	"github.com/transparency-dev/merkle/rfc6962"
 	"github.com/transparency-dev/merkle/testonly"
 )
@@ -183,6 +186,122 @@ func TestAppend(t *testing.T) {
 	}
 }
 
+func TestAppendLeaf(t *testing.T) {
+	rf := &compact.RangeFactory{Hash: factory.Hash, LeafHash: hashLeaf}
+	const size = uint64(100)
+	tree, visit := newTree(t, size)
+	cr1 := rf.NewEmptyRange(0)
+	cr2 := rf.NewEmptyRange(0)
+	for i := uint64(0); i < size; i++ {
+		if err := cr1.AppendLeaf(leafData(i), visit); err != nil {
+			t.Fatalf("AppendLeaf: %v", err)
+		}
+		if err := cr2.Append(hashLeaf(leafData(i)), nil); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	if !cr1.Equal(cr2) {
+		t.Error("AppendLeaf and Append ranges mismatch")
+	}
+	tree.verifyRange(t, cr1, true)
+	tree.verifyAllVisited(t, cr1)
+
+	if err := factory.NewEmptyRange(0).AppendLeaf(leafData(0), nil); err == nil {
+		t.Error("AppendLeaf succeeded without a leaf hash function")
+	}
+}
+
+func TestSpine(t *testing.T) {
+	const size = uint64(13)
+	tree, _ := newTree(t, size)
+	for begin := uint64(0); begin <= size; begin++ {
+		rng := newRangeOf(t, tree, begin, size)
+		ids := rng.Spine()
+		if diff := cmp.Diff(ids, compact.RangeNodes(begin, size, nil), cmpopts.EquateEmpty()); diff != "" {
+			t.Fatalf("Spine(%d, %d): diff(-got +want):\n%s", begin, size, diff)
+		}
+		for i, id := range ids {
+			if got, want := rng.Hashes()[i], tree.nodes[id.Level][id.Index].hash; !bytes.Equal(got, want) {
+				t.Errorf("Spine(%d, %d): node %+v hash mismatch", begin, size, id)
+			}
+		}
+	}
+
+	id := compact.NewNodeID
+	want := []compact.NodeID{id(3, 0), id(2, 2), id(0, 12)}
+	if diff := cmp.Diff(newRangeOf(t, tree, 0, size).Spine(), want); diff != "" {
+		t.Errorf("Spine: diff(-got +want):\n%s", diff)
+	}
+}
+
+func TestExtends(t *testing.T) {
+	const size = uint64(37)
+	tree, _ := newTree(t, size)
+	for size2 := uint64(0); size2 <= size; size2++ {
+		r2 := newRangeOf(t, tree, 0, size2)
+		for size1 := uint64(0); size1 <= size2; size1++ {
+			r1 := newRangeOf(t, tree, 0, size1)
+			ok, err := r1.Extends(r2)
+			// The check is possible iff r2 can derive its root at size1.
+			if _, rootErr := r2.RootAt(size1); rootErr != nil {
+				if err == nil {
+					t.Errorf("Extends(%d, %d): got %v, want error", size1, size2, ok)
+				}
+			} else if err != nil || !ok {
+				t.Errorf("Extends(%d, %d): got %v, %v; want true", size1, size2, ok, err)
+			}
+		}
+	}
+
+	// A fork of the tree, with a different leaf #5.
+	fork := make([][]byte, 20)
+	for i := range fork {
+		fork[i] = tree.leaf(uint64(i))
+	}
+	fork[5] = hashLeaf([]byte("fork"))
+	forkRange := func(end uint64) *compact.Range {
+		rng := factory.NewEmptyRange(0)
+		for _, hash := range fork[:end] {
+			if err := rng.Append(hash, nil); err != nil {
+				t.Fatalf("Append: %v", err)
+			}
+		}
+		return rng
+	}
+	for _, tc := range []struct {
+		size1, size2 uint64
+		wantErr      bool
+	}{
+		{size1: 8, size2: 8},
+		{size1: 16, size2: 20},
+		{size1: 6, size2: 20, wantErr: true}, // The root at size 6 can't be derived.
+	} {
+		for _, rngs := range [][2]*compact.Range{
+			{forkRange(tc.size1), newRangeOf(t, tree, 0, tc.size2)},
+			{newRangeOf(t, tree, 0, tc.size1), forkRange(tc.size2)},
+		} {
+			ok, err := rngs[0].Extends(rngs[1])
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("Extends(%d, %d): %v, want error %v", tc.size1, tc.size2, err, tc.wantErr)
+			} else if ok {
+				t.Errorf("Extends(%d, %d): got true for a forked range", tc.size1, tc.size2)
+			}
+		}
+	}
+
+	r1, r2 := newRangeOf(t, tree, 0, 5), newRangeOf(t, tree, 1, 5)
+	if _, err := r1.Extends(r2); err == nil {
+		t.Error("Extends accepted a range not beginning at 0")
+	}
+	if _, err := newRangeOf(t, tree, 0, 6).Extends(r1); err == nil {
+		t.Error("Extends accepted a shorter range")
+	}
+	other := &compact.RangeFactory{Hash: factory.Hash}
+	if _, err := r1.Extends(other.NewEmptyRange(0)); err == nil {
+		t.Error("Extends accepted an incompatible range")
+	}
+}
+
 func TestGoldenRanges(t *testing.T) {
 	inputs := testonly.LeafInputs()
 	roots := testonly.RootHashes()
@@ -329,6 +448,10 @@ func TestNewRange(t *testing.T) {
 	if err == nil {
 		t.Error("NewRange succeeded unexpectedly")
 	}
+	_, err = factory.NewRange(rng.Begin(), rng.End(), rng.Hashes()[1:])
+	if err == nil {
+		t.Error("NewRange succeeded unexpectedly")
+	}
 	// The number of hashes does not correspond to the range.
 	_, err = factory.NewRange(rng.Begin(), rng.End()-1, rng.Hashes())
 	if err == nil {
@@ -343,32 +466,58 @@ func TestNewRange(t *testing.T) {
 	tree.verifyRange(t, rng1, false)
 }
 
-func TestNewRangeWithStorage(t *testing.T) {
-	const numNodes = uint64(777)
+func TestNewRangeVerified(t *testing.T) {
+	const numNodes = uint64(123)
 	tree, _ := newTree(t, numNodes)
 	root := tree.rootHash()
-
-	nodes := make(map[compact.NodeID][]byte)
-	getHashes := func(ids []compact.NodeID) [][]byte {
-		hashes := make([][]byte, len(ids))
-		for i, id := range ids {
-			hashes[i] = nodes[id]
+	rng := factory.NewEmptyRange(0)
+	for i := uint64(0); i < numNodes; i++ {
+		if err := rng.Append(tree.leaf(i), nil); err != nil {
+			t.Fatalf("Append: %v", err)
 		}
-		return hashes
 	}
 
+	rng1, err := factory.NewRangeVerified(0, numNodes, rng.Hashes(), root)
+	if err != nil {
+		t.Fatalf("NewRangeVerified: %v", err)
+	}
+	tree.verifyRange(t, rng1, true)
+
+	if _, err := factory.NewRangeVerified(0, numNodes, rng.Hashes(), tree.leaf(0)); err == nil {
+		t.Error("NewRangeVerified accepted a wrong root")
+	}
+	if _, err := factory.NewRangeVerified(1, numNodes, rng.Hashes()[1:], root); err == nil {
+		t.Error("NewRangeVerified accepted a range not starting at 0")
+	}
+	if _, err := factory.NewRangeVerified(0, numNodes, rng.Hashes()[1:], root); err == nil {
+		t.Error("NewRangeVerified accepted a wrong number of hashes")
+	}
+
+	// Tamper with one of the hashes.
+	hashes := append([][]byte{}, rng.Hashes()...)
+	hashes[1] = append([]byte{}, hashes[1]...)
+	hashes[1][0] ^= 1
+	if _, err := factory.NewRangeVerified(0, numNodes, hashes, root); err == nil {
+		t.Error("NewRangeVerified accepted tampered hashes")
+	}
+}
+
+func TestNewRangeWithStorage(t *testing.T) {
+	const numNodes = uint64(777)
+	tree, _ := newTree(t, numNodes)
+	root := tree.rootHash()
+
+	nodes := make(compact.NodeMap)
 	cr := factory.NewEmptyRange(0)
 	for i := uint64(0); i < numNodes; i++ {
-		nodes[compact.NewNodeID(0, i)] = tree.leaf(i)
 		if err := cr.Append(tree.leaf(i), func(id compact.NodeID, hash []byte) {
 			nodes[id] = hash
 		}); err != nil {
 			t.Fatalf("%d: Append: %v", i, err)
 		}
-		hashes := getHashes(compact.RangeNodes(0, i+1, nil))
 		var err error
-		if cr, err = factory.NewRange(0, i+1, hashes); err != nil {
-			t.Fatalf("%d: NewRange: %v", i+1, err)
+		if cr, err = factory.NewRangeFromNodes(0, i+1, nodes); err != nil {
+			t.Fatalf("%d: NewRangeFromNodes: %v", i+1, err)
 		}
 	}
 
@@ -379,6 +528,20 @@ func TestNewRangeWithStorage(t *testing.T) {
 	if !bytes.Equal(got, root) {
 		t.Fatalf("Got root hash %x, want %x", got, root)
 	}
+
+	// A range in the middle of the tree.
+	if cr, err = factory.NewRangeFromNodes(5, 700, nodes); err != nil {
+		t.Fatalf("NewRangeFromNodes: %v", err)
+	}
+	tree.verifyRange(t, cr, true)
+
+	delete(nodes, compact.NewNodeID(9, 0))
+	if _, err := factory.NewRangeFromNodes(0, 700, nodes); err == nil {
+		t.Error("NewRangeFromNodes succeeded with a missing node")
+	}
+	if _, err := factory.NewRangeFromNodes(10, 9, nodes); err == nil {
+		t.Error("NewRangeFromNodes succeeded with end < begin")
+	}
 }
 
 func TestGetRootHash(t *testing.T) {
@@ -408,6 +571,163 @@ func TestGetRootHash(t *testing.T) {
 	}
 }
 
+func TestRootAt(t *testing.T) {
+	const size = uint64(40)
+	tree, _ := newTree(t, size)
+	for end := uint64(0); end <= size; end++ {
+		rng := newRangeOf(t, tree, 0, end)
+		for size2 := uint64(0); size2 <= end; size2++ {
+			want, err := newRangeOf(t, tree, 0, size2).GetRootHash(nil)
+			if err != nil {
+				t.Fatalf("GetRootHash: %v", err)
+			}
+			// The root is derivable iff the compact ranges share the prefix.
+			derivable := slices.Equal(rng.Spine()[:min(compact.RangeSize(0, size2), len(rng.Spine()))], compact.RangeNodes(0, size2, nil))
+			got, err := rng.RootAt(size2)
+			if gotErr := err != nil; gotErr != !derivable {
+				t.Errorf("[0, %d): RootAt(%d): %v, want error %v", end, size2, err, !derivable)
+			} else if err == nil && !bytes.Equal(got, want) {
+				t.Errorf("[0, %d): RootAt(%d): got %x, want %x", end, size2, shorten(got), shorten(want))
+			}
+		}
+		if _, err := rng.RootAt(end + 1); err == nil {
+			t.Errorf("[0, %d): RootAt(%d) succeeded", end, end+1)
+		}
+	}
+
+	if _, err := newRangeOf(t, tree, 0, 8).RootAt(5); err == nil {
+		t.Error("RootAt(5) succeeded for range [0, 8)")
+	}
+	if _, err := newRangeOf(t, tree, 1, 8).RootAt(4); err == nil {
+		t.Error("RootAt succeeded for range not starting at 0")
+	}
+}
+
+func TestInclusionProofLen(t *testing.T) {
+	const size = uint64(70)
+	tree, _ := newTree(t, size)
+	for end := uint64(0); end <= size; end++ {
+		rng := newRangeOf(t, tree, 0, end)
+		for index := uint64(0); index < end; index++ {
+			got, err := rng.InclusionProofLen(index)
+			if err != nil {
+				t.Fatalf("InclusionProofLen(%d): %v", index, err)
+			}
+			nodes, err := proof.Inclusion(index, end)
+			if err != nil {
+				t.Fatalf("Inclusion: %v", err)
+			}
+			// The ephemeral nodes of the proof are rehashed into one.
+			want := len(nodes.IDs)
+			if _, b, e := nodes.Ephem(); e > b {
+				want -= e - b - 1
+			}
+			if got != want {
+				t.Errorf("[0, %d): InclusionProofLen(%d): got %d, want %d", end, index, got, want)
+			}
+		}
+		if _, err := rng.InclusionProofLen(end); err == nil {
+			t.Errorf("[0, %d): InclusionProofLen(%d) succeeded", end, end)
+		}
+	}
+}
+
+func TestConsistencyAsRangeDelta(t *testing.T) {
+	id := compact.NewNodeID
+	prefix, delta := compact.ConsistencyAsRangeDelta(3, 7)
+	if diff := cmp.Diff([]compact.NodeID{id(1, 0), id(0, 2)}, prefix); diff != "" {
+		t.Errorf("prefix: diff (-want +got)\n%s", diff)
+	}
+	if diff := cmp.Diff([]compact.NodeID{id(0, 3), id(1, 2), id(0, 6)}, delta); diff != "" {
+		t.Errorf("delta: diff (-want +got)\n%s", diff)
+	}
+
+	// Cross-check with the nodes of consistency proofs.
+	less := func(a, b compact.NodeID) bool { return a.Less(b) }
+	for size2 := uint64(0); size2 <= 70; size2++ {
+		for size1 := uint64(0); size1 <= size2+1; size1++ {
+			prefix, delta := compact.ConsistencyAsRangeDelta(size1, size2)
+			got := append(prefix, delta...)
+			var want []compact.NodeID
+			if size1 != 0 && size1 <= size2 {
+				nodes, err := proof.Consistency(size1, size2)
+				if err != nil {
+					t.Fatalf("Consistency: %v", err)
+				}
+				want = nodes.IDs
+			}
+			if diff := cmp.Diff(want, got, cmpopts.SortSlices(less), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("ConsistencyAsRangeDelta(%d, %d): diff (-want +got)\n%s", size1, size2, diff)
+			}
+		}
+	}
+}
+
+func TestPathHashes(t *testing.T) {
+	const size = uint64(21)
+	tree, _ := newTree(t, size)
+	// refHash returns the hash of the [begin, end) subtree, which is
+	// ephemeral if end-begin is not a power of 2.
+	var refHash func(begin, end uint64) []byte
+	refHash = func(begin, end uint64) []byte {
+		if end-begin == 1 {
+			return tree.leaf(begin)
+		}
+		split := begin + uint64(1)<<(bits.Len64(end-begin-1)-1)
+		return factory.Hash(refHash(begin, split), refHash(split, end))
+	}
+
+	for begin := uint64(0); begin < size; begin++ {
+		for end := begin + 1; end <= size; end++ {
+			rng := newRangeOf(t, tree, begin, end)
+			for index := begin; index < end; index++ {
+				var ids []compact.NodeID
+				rng.PathHashes(index, func(id compact.NodeID, hash []byte) bool {
+					ids = append(ids, id)
+					b, e := id.Coverage()
+					if b < begin {
+						t.Errorf("[%d, %d): index %d: node %+v begins before the range", begin, end, index, id)
+					} else if want := refHash(b, min(e, end)); !bytes.Equal(hash, want) {
+						t.Errorf("[%d, %d): index %d: node %+v: hash %x, want %x", begin, end, index, id, shorten(hash), shorten(want))
+					}
+					return true
+				})
+				if len(ids) == 0 {
+					t.Fatalf("[%d, %d): index %d: no nodes", begin, end, index)
+				}
+				for i, id := range ids {
+					if b, e := id.Coverage(); index < b || index >= e {
+						t.Errorf("[%d, %d): index %d: node %+v is not on the path", begin, end, index, id)
+					}
+					if i != 0 && id.Level <= ids[i-1].Level {
+						t.Errorf("[%d, %d): index %d: node %+v is not above %+v", begin, end, index, id, ids[i-1])
+					}
+				}
+				if begin == 0 {
+					last := ids[len(ids)-1]
+					if b, e := last.Coverage(); b != 0 || e < end {
+						t.Errorf("[0, %d): index %d: path ends at %+v, want root", end, index, last)
+					}
+				}
+			}
+		}
+	}
+
+	rng := newRangeOf(t, tree, 0, size)
+	var calls int
+	rng.PathHashes(3, func(compact.NodeID, []byte) bool {
+		calls++
+		return false
+	})
+	if calls != 1 {
+		t.Errorf("PathHashes: yield called %d times after returning false, want 1", calls)
+	}
+	rng.PathHashes(size, func(id compact.NodeID, _ []byte) bool {
+		t.Errorf("PathHashes: got node %+v for index out of range", id)
+		return true
+	})
+}
+
 func TestGetRootHashGolden(t *testing.T) {
 	type node struct {
 		level uint
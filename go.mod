@@ -3,3 +3,5 @@ module github.com/transparency-dev/merkle
 go 1.22.7
 
 require github.com/google/go-cmp v0.6.0
+
+require golang.org/x/mod v0.23.0
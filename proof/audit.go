@@ -0,0 +1,68 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proof
+
+import (
+	"fmt"
+
+	"github.com/transparency-dev/merkle"
+	"github.com/transparency-dev/merkle/compact"
+)
+
+// Auditor checks that a stream of leaf hashes makes up the tree of a known
+// size and root hash. It keeps only the compact range of the leaves seen so
+// far, so the memory use is logarithmic in the tree size.
+type Auditor struct {
+	hasher merkle.LogHasher
+	root   []byte
+	size   uint64
+	rng    *compact.Range
+}
+
+// NewAuditor returns an Auditor that expects exactly size leaf hashes, and the
+// tree made of them to have the given root hash.
+func NewAuditor(hasher merkle.LogHasher, root []byte, size uint64) *Auditor {
+	rf := &compact.RangeFactory{Hash: hasher.HashChildren}
+	return &Auditor{hasher: hasher, root: root, size: size, rng: rf.NewEmptyRange(0)}
+}
+
+// Add appends the next leaf hash to the audited tree. Returns an error if the
+// expected number of leaves has already been added.
+func (a *Auditor) Add(leafHash []byte) error {
+	if end := a.rng.End(); end >= a.size {
+		return fmt.Errorf("leaf %d is beyond tree size %d", end, a.size)
+	}
+	if got, want := len(leafHash), a.hasher.Size(); got != want {
+		return fmt.Errorf("leafHash has unexpected size %d, want %d", got, want)
+	}
+	return a.rng.Append(leafHash, nil)
+}
+
+// Done checks that all the expected leaves have been added, and that the
+// resulting tree has the expected root hash. Returns RootMismatchError if the
+// root hashes differ.
+func (a *Auditor) Done() error {
+	if end := a.rng.End(); end != a.size {
+		return fmt.Errorf("got %d leaves, want %d", end, a.size)
+	}
+	root := a.hasher.EmptyRoot()
+	if a.size != 0 {
+		var err error
+		if root, err = a.rng.GetRootHash(nil); err != nil {
+			return err
+		}
+	}
+	return verifyMatch(root, a.root, a.size)
+}
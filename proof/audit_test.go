@@ -0,0 +1,79 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proof
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/transparency-dev/merkle/compact"
+)
+
+func TestAuditor(t *testing.T) {
+	const size = uint64(1000)
+	nodes, roots := buildTree(t, size)
+	leafHash := func(index uint64) []byte {
+		return nodes[compact.NewNodeID(0, index)]
+	}
+
+	for _, size := range []uint64{0, 1, 7, 8, size} {
+		a := NewAuditor(hasher, roots[size], size)
+		for i := uint64(0); i < size; i++ {
+			if err := a.Add(leafHash(i)); err != nil {
+				t.Fatalf("Add(%d): %v", i, err)
+			}
+		}
+		if err := a.Done(); err != nil {
+			t.Errorf("size %d: Done: %v", size, err)
+		}
+		if err := a.Add(sha256SomeHash); err == nil {
+			t.Errorf("size %d: Add succeeded beyond tree size", size)
+		}
+	}
+
+	t.Run("corrupted", func(t *testing.T) {
+		a := NewAuditor(hasher, roots[size], size)
+		for i := uint64(0); i < size; i++ {
+			hash := leafHash(i)
+			if i == 567 {
+				hash = sha256SomeHash
+			}
+			if err := a.Add(hash); err != nil {
+				t.Fatalf("Add(%d): %v", i, err)
+			}
+		}
+		var mismatch RootMismatchError
+		if err := a.Done(); !errors.As(err, &mismatch) {
+			t.Errorf("Done: %v, want RootMismatchError", err)
+		}
+	})
+
+	t.Run("incomplete", func(t *testing.T) {
+		a := NewAuditor(hasher, roots[size], size)
+		if err := a.Add(leafHash(0)); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+		if err := a.Done(); err == nil {
+			t.Error("Done succeeded with missing leaves")
+		}
+	})
+
+	t.Run("bad-hash", func(t *testing.T) {
+		a := NewAuditor(hasher, roots[size], size)
+		if err := a.Add(sha256SomeHash[1:]); err == nil {
+			t.Error("Add accepted a short hash")
+		}
+	})
+}
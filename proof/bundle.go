@@ -0,0 +1,103 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proof
+
+import (
+	"fmt"
+
+	"github.com/transparency-dev/merkle/compact"
+)
+
+// Bundle contains information on how to fetch and construct an inclusion
+// proof and a consistency proof that are served together, such that the nodes
+// shared by the two proofs are fetched only once.
+type Bundle struct {
+	// IDs contains the deduplicated IDs of the nodes sufficient to build both
+	// proofs.
+	IDs []compact.NodeID
+
+	inclusion   Nodes
+	consistency Nodes
+	// inclIdx and consIdx map the positions in the inclusion.IDs and
+	// consistency.IDs lists correspondingly to positions in the IDs list.
+	inclIdx []int
+	consIdx []int
+}
+
+// PlanBundle returns the information on how to fetch and construct the
+// inclusion proof for the given leaf index in the tree of size1, and the
+// consistency proof between tree sizes size1 and size2. It requires
+// 0 <= index < size1 <= size2.
+func PlanBundle(index, size1, size2 uint64) (Bundle, error) {
+	incl, err := Inclusion(index, size1)
+	if err != nil {
+		return Bundle{}, err
+	}
+	cons, err := Consistency(size1, size2)
+	if err != nil {
+		return Bundle{}, err
+	}
+
+	b := Bundle{
+		IDs:         make([]compact.NodeID, 0, len(incl.IDs)+len(cons.IDs)),
+		inclusion:   incl,
+		consistency: cons,
+	}
+	pos := make(map[compact.NodeID]int, cap(b.IDs))
+	add := func(ids []compact.NodeID) []int {
+		idx := make([]int, len(ids))
+		for i, id := range ids {
+			p, ok := pos[id]
+			if !ok {
+				p = len(b.IDs)
+				pos[id] = p
+				b.IDs = append(b.IDs, id)
+			}
+			idx[i] = p
+		}
+		return idx
+	}
+	b.inclIdx = add(incl.IDs)
+	b.consIdx = add(cons.IDs)
+	return b, nil
+}
+
+// Rehash computes the inclusion and consistency proofs based on the slice of
+// node hashes corresponding to their IDs in the b.IDs field. The slices must
+// be of the same length. The hc parameter computes a node's hash based on
+// hashes of its children.
+//
+// Unlike Nodes.Rehash, this method does not modify the passed-in slice.
+func (b Bundle) Rehash(h [][]byte, hc func(left, right []byte) []byte) ([][]byte, [][]byte, error) {
+	if got, want := len(h), len(b.IDs); got != want {
+		return nil, nil, fmt.Errorf("got %d hashes but expected %d", got, want)
+	}
+	pick := func(idx []int) [][]byte {
+		res := make([][]byte, len(idx))
+		for i, p := range idx {
+			res[i] = h[p]
+		}
+		return res
+	}
+	incl, err := b.inclusion.Rehash(pick(b.inclIdx), hc)
+	if err != nil {
+		return nil, nil, err
+	}
+	cons, err := b.consistency.Rehash(pick(b.consIdx), hc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return incl, cons, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proof
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/transparency-dev/merkle/compact"
+)
+
+func TestPlanBundle(t *testing.T) {
+	const size = uint64(40)
+	nodes, roots := buildTree(t, size)
+	for size2 := uint64(1); size2 <= size; size2++ {
+		for size1 := uint64(1); size1 <= size2; size1++ {
+			for index := uint64(0); index < size1; index++ {
+				b, err := PlanBundle(index, size1, size2)
+				if err != nil {
+					t.Fatalf("PlanBundle: %v", err)
+				}
+				// Check that the IDs are deduplicated.
+				seen := make(map[compact.NodeID]bool)
+				for _, id := range b.IDs {
+					if seen[id] {
+						t.Fatalf("PlanBundle(%d, %d, %d): duplicate ID %+v", index, size1, size2, id)
+					}
+					seen[id] = true
+				}
+
+				incl, cons, err := b.Rehash(getHashes(nodes, b.IDs), hasher.HashChildren)
+				if err != nil {
+					t.Fatalf("Rehash: %v", err)
+				}
+				leafHash := nodes[compact.NewNodeID(0, index)]
+				if err := VerifyInclusion(hasher, index, size1, leafHash, incl, roots[size1]); err != nil {
+					t.Errorf("PlanBundle(%d, %d, %d): VerifyInclusion: %v", index, size1, size2, err)
+				}
+				if err := VerifyConsistency(hasher, size1, size2, cons, roots[size1], roots[size2]); err != nil {
+					t.Errorf("PlanBundle(%d, %d, %d): VerifyConsistency: %v", index, size1, size2, err)
+				}
+			}
+		}
+	}
+}
+
+func TestPlanBundleDedup(t *testing.T) {
+	// The inclusion proof for leaf 3 in the tree of size 5 is {c, g, e}, and
+	// the consistency proof between sizes 5 and 7 is {e, f, j, k}. See the tree
+	// diagram of TestConsistency. Node e is shared.
+	b, err := PlanBundle(3, 5, 7)
+	if err != nil {
+		t.Fatalf("PlanBundle: %v", err)
+	}
+	if got, want := len(b.IDs), 6; got != want {
+		t.Errorf("PlanBundle: got %d IDs, want %d", got, want)
+	}
+	if _, _, err := b.Rehash(nil, hasher.HashChildren); err == nil {
+		t.Error("Rehash accepted a wrong number of hashes")
+	}
+}
+
+func TestPlanBundleErrors(t *testing.T) {
+	for _, tc := range []struct {
+		index, size1, size2 uint64
+	}{
+		{index: 0, size1: 0, size2: 0},
+		{index: 5, size1: 5, size2: 7},
+		{index: 1, size1: 5, size2: 4},
+	} {
+		t.Run(fmt.Sprintf("%d:%d:%d", tc.index, tc.size1, tc.size2), func(t *testing.T) {
+			if _, err := PlanBundle(tc.index, tc.size1, tc.size2); err == nil {
+				t.Error("PlanBundle succeeded unexpectedly")
+			}
+		})
+	}
+}
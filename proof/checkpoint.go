@@ -0,0 +1,63 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proof
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/transparency-dev/merkle"
+	"golang.org/x/mod/sumdb/note"
+)
+
+// VerifyInclusionAgainstCheckpoint verifies the inclusion proof like
+// VerifyInclusion, but takes the tree size and root hash from the given signed
+// checkpoint. The checkpoint is a note in the https://c2sp.org/tlog-checkpoint
+// format, which must be signed by the verifier and have the given origin.
+func VerifyInclusionAgainstCheckpoint(hasher merkle.LogHasher, index uint64, leafHash []byte, proof [][]byte, checkpoint []byte, origin string, verifier note.Verifier) error {
+	n, err := note.Open(checkpoint, note.VerifierList(verifier))
+	if err != nil {
+		return fmt.Errorf("failed to verify checkpoint: %w", err)
+	}
+	size, root, err := parseCheckpoint(n.Text, origin)
+	if err != nil {
+		return err
+	}
+	return VerifyInclusion(hasher, index, size, leafHash, proof, root)
+}
+
+// parseCheckpoint returns the tree size and root hash from the body of a
+// checkpoint with the given origin.
+func parseCheckpoint(text, origin string) (uint64, []byte, error) {
+	lines := strings.SplitN(text, "\n", 4)
+	if len(lines) < 4 {
+		return 0, nil, errors.New("invalid checkpoint: too few lines")
+	}
+	if got := lines[0]; got != origin {
+		return 0, nil, fmt.Errorf("checkpoint origin %q, want %q", got, origin)
+	}
+	size, err := strconv.ParseUint(lines[1], 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid checkpoint size: %v", err)
+	}
+	root, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid checkpoint root hash: %v", err)
+	}
+	return size, root, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proof
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/transparency-dev/merkle/compact"
+	"golang.org/x/mod/sumdb/note"
+)
+
+const testOrigin = "example.com/log"
+
+func TestVerifyInclusionAgainstCheckpoint(t *testing.T) {
+	skey, vkey, err := note.GenerateKey(rand.Reader, "example.com")
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	signer, err := note.NewSigner(skey)
+	if err != nil {
+		t.Fatalf("NewSigner: %v", err)
+	}
+	verifier, err := note.NewVerifier(vkey)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	_, otherKey, err := note.GenerateKey(rand.Reader, "example.com")
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	otherVerifier, err := note.NewVerifier(otherKey)
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+	sign := func(text string) []byte {
+		t.Helper()
+		msg, err := note.Sign(&note.Note{Text: text}, signer)
+		if err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		return msg
+	}
+
+	const size, index = uint64(13), uint64(6)
+	nodes, roots := buildTree(t, size)
+	leafHash := nodes[compact.NewNodeID(0, index)]
+	n, err := Inclusion(index, size)
+	if err != nil {
+		t.Fatalf("Inclusion: %v", err)
+	}
+	proof, err := n.Rehash(getHashes(nodes, n.IDs), hasher.HashChildren)
+	if err != nil {
+		t.Fatalf("Rehash: %v", err)
+	}
+	body := func(origin string, size uint64, root []byte) string {
+		return fmt.Sprintf("%s\n%d\n%s\n", origin, size, base64.StdEncoding.EncodeToString(root))
+	}
+
+	for _, tc := range []struct {
+		desc       string
+		checkpoint []byte
+		origin     string
+		verifier   note.Verifier
+		wantErr    bool
+		wantRoot   bool // Whether the error must be RootMismatchError.
+	}{
+		{desc: "ok", checkpoint: sign(body(testOrigin, size, roots[size]))},
+		{desc: "ok-extension", checkpoint: sign(body(testOrigin, size, roots[size]) + "extension\n")},
+		{desc: "wrong-verifier", checkpoint: sign(body(testOrigin, size, roots[size])), verifier: otherVerifier, wantErr: true},
+		{desc: "wrong-origin", checkpoint: sign(body("other.com/log", size, roots[size])), wantErr: true},
+		{desc: "wrong-root", checkpoint: sign(body(testOrigin, size, roots[size-1])), wantErr: true, wantRoot: true},
+		{desc: "wrong-size", checkpoint: sign(body(testOrigin, size+8, roots[size])), wantErr: true},
+		{desc: "bad-size", checkpoint: sign(testOrigin + "\nX\nAAAA\n"), wantErr: true},
+		{desc: "bad-root", checkpoint: sign(testOrigin + "\n13\n!!!\n"), wantErr: true},
+		{desc: "short", checkpoint: sign(testOrigin + "\n13\n"), wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			v := tc.verifier
+			if v == nil {
+				v = verifier
+			}
+			err := VerifyInclusionAgainstCheckpoint(hasher, index, leafHash, proof, tc.checkpoint, testOrigin, v)
+			if got := err != nil; got != tc.wantErr {
+				t.Fatalf("VerifyInclusionAgainstCheckpoint: %v, want error %v", err, tc.wantErr)
+			}
+			var mismatch RootMismatchError
+			if got := errors.As(err, &mismatch); got != tc.wantRoot {
+				t.Errorf("VerifyInclusionAgainstCheckpoint: %v, want RootMismatchError %v", err, tc.wantRoot)
+			}
+		})
+	}
+}
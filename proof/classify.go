@@ -0,0 +1,46 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proof
+
+// Kind is a kind of proof.
+type Kind int
+
+// Kinds of proofs.
+const (
+	// Unknown means that the kind of the proof can't be determined.
+	Unknown Kind = iota
+	// InclusionKind is an inclusion proof, see Inclusion.
+	InclusionKind
+	// ConsistencyKind is a consistency proof, see Consistency.
+	ConsistencyKind
+)
+
+// Classify returns the kind of the proof of the given length, based on the
+// claimed parameters: either an inclusion proof for the leaf index in the tree
+// of size2, or a consistency proof between size1 and size2. Returns false if
+// the length matches neither kind of proof, or matches both, in which case the
+// proof is ambiguous. This only checks the proof shape, and does not verify
+// the proof.
+func Classify(proofLen int, size1, size2, index uint64) (Kind, bool) {
+	incl := proofLen == inclusionProofSize(index, size2)
+	cons := proofLen == ConsistencyProofSize(size1, size2)
+	switch {
+	case incl && !cons:
+		return InclusionKind, true
+	case cons && !incl:
+		return ConsistencyKind, true
+	}
+	return Unknown, false
+}
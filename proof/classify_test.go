@@ -0,0 +1,76 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proof
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestInclusionProofSize(t *testing.T) {
+	for _, p := range inclusionProofs[1:] {
+		if got, want := inclusionProofSize(p.leaf-1, p.size), len(p.proof); got != want {
+			t.Errorf("inclusionProofSize(%d, %d): got %d, want %d", p.leaf-1, p.size, got, want)
+		}
+	}
+	for size := uint64(0); size <= 70; size++ {
+		for index := uint64(0); index < size; index++ {
+			n, err := Inclusion(index, size)
+			if err != nil {
+				t.Fatalf("Inclusion: %v", err)
+			}
+			_, begin, end := n.Ephem()
+			want := len(n.IDs)
+			if end > begin {
+				want -= end - begin - 1
+			}
+			if got := inclusionProofSize(index, size); got != want {
+				t.Errorf("inclusionProofSize(%d, %d): got %d, want %d", index, size, got, want)
+			}
+		}
+	}
+	if got := inclusionProofSize(5, 5); got != -1 {
+		t.Errorf("inclusionProofSize(5, 5): got %d, want -1", got)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		proofLen            int
+		size1, size2, index uint64
+		want                Kind
+		wantOK              bool
+	}{
+		// Inclusion proof for leaf 2 in a tree of size 7 has 3 hashes, and the
+		// consistency proof between sizes 4 and 7 has 1 hash.
+		{proofLen: 3, size1: 4, size2: 7, index: 2, want: InclusionKind, wantOK: true},
+		{proofLen: 1, size1: 4, size2: 7, index: 2, want: ConsistencyKind, wantOK: true},
+		{proofLen: 4, size1: 4, size2: 7, index: 2},
+		// Both proofs have 2 hashes.
+		{proofLen: 2, size1: 2, size2: 7, index: 6},
+		// No consistency proof from an empty tree.
+		{proofLen: 0, size1: 0, size2: 1, index: 0, want: InclusionKind, wantOK: true},
+		// The index is out of bounds.
+		{proofLen: 0, size1: 5, size2: 5, index: 5, want: ConsistencyKind, wantOK: true},
+		{proofLen: 1, size1: 5, size2: 3, index: 5},
+	} {
+		t.Run(fmt.Sprintf("%d:%d:%d:%d", tc.proofLen, tc.size1, tc.size2, tc.index), func(t *testing.T) {
+			got, ok := Classify(tc.proofLen, tc.size1, tc.size2, tc.index)
+			if got != tc.want || ok != tc.wantOK {
+				t.Errorf("Classify: got (%v, %v), want (%v, %v)", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proof
+
+import (
+	"fmt"
+
+	"github.com/transparency-dev/merkle"
+)
+
+// FlatProofSet is a batch of inclusion proofs that stores all hashes in a
+// single byte slice. It is intended for verifying large numbers of proofs
+// without allocating a slice of hashes for each of them.
+//
+// The zero value is an empty set. All hashes added to the set must have the
+// same size as the first added leaf hash.
+type FlatProofSet struct {
+	hashSize int
+	entries  []flatEntry
+	data     []byte
+}
+
+// flatEntry describes one inclusion proof in a FlatProofSet. The leaf hash,
+// the root hash and the proof hashes are stored contiguously in the data slice
+// starting at offset.
+type flatEntry struct {
+	index, size uint64
+	offset      int
+	hashes      int // The number of proof hashes.
+}
+
+// Len returns the number of proofs in the set.
+func (s *FlatProofSet) Len() int {
+	return len(s.entries)
+}
+
+// Add appends the inclusion proof for the leaf with the given index and hash,
+// relatively to the tree of the given size and root hash, to the set. The
+// proofHashes is a concatenation of the proof hashes. The data is copied, so
+// the passed in slices can be reused after this call.
+func (s *FlatProofSet) Add(index, size uint64, leafHash, root []byte, proofHashes []byte) error {
+	hashSize := s.hashSize
+	if len(s.entries) == 0 {
+		hashSize = len(leafHash)
+	}
+	if hashSize == 0 {
+		return fmt.Errorf("empty leaf hash")
+	} else if got := len(leafHash); got != hashSize {
+		return fmt.Errorf("leafHash has unexpected size %d, want %d", got, hashSize)
+	} else if got := len(root); got != hashSize {
+		return fmt.Errorf("root has unexpected size %d, want %d", got, hashSize)
+	} else if got := len(proofHashes); got%hashSize != 0 {
+		return fmt.Errorf("proof length %d is not a multiple of hash size %d", got, hashSize)
+	}
+	s.hashSize = hashSize
+	s.entries = append(s.entries, flatEntry{
+		index:  index,
+		size:   size,
+		offset: len(s.data),
+		hashes: len(proofHashes) / hashSize,
+	})
+	s.data = append(s.data, leafHash...)
+	s.data = append(s.data, root...)
+	s.data = append(s.data, proofHashes...)
+	return nil
+}
+
+// VerifyAll verifies all the inclusion proofs in the set, like
+// VerifyInclusion does. Returns the error for the first proof that does not
+// verify, annotated with its position in the set.
+func (s *FlatProofSet) VerifyAll(hasher merkle.LogHasher) error {
+	if len(s.entries) != 0 && s.hashSize != hasher.Size() {
+		return fmt.Errorf("hash size %d does not match hasher size %d", s.hashSize, hasher.Size())
+	}
+	for i, e := range s.entries {
+		if err := s.verify(hasher, e); err != nil {
+			return fmt.Errorf("proof %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (s *FlatProofSet) verify(hasher merkle.LogHasher, e flatEntry) error {
+	if e.index >= e.size {
+		return fmt.Errorf("index is beyond size: %d >= %d", e.index, e.size)
+	}
+	inner, border := decompInclProof(e.index, e.size)
+	if got, want := e.hashes, inner+border; want == 0 && got != 0 {
+		return fmt.Errorf("size=%d, but got %d hashes: %w", e.size, got, ErrEmptyProofExpected)
+	} else if got != want {
+		return fmt.Errorf("wrong proof size %d, want %d", got, want)
+	}
+	hash := func(i int) []byte {
+		begin := e.offset + i*s.hashSize
+		end := begin + s.hashSize
+		return s.data[begin:end:end]
+	}
+	// The leaf hash is at position 0, the root at 1, and the proof follows.
+	res := hash(0)
+	for i := 0; i < inner; i++ {
+		if (e.index>>uint(i))&1 == 0 {
+			res = hasher.HashChildren(res, hash(i+2))
+		} else {
+			res = hasher.HashChildren(hash(i+2), res)
+		}
+	}
+	for i := inner; i < e.hashes; i++ {
+		res = hasher.HashChildren(hash(i+2), res)
+	}
+	return verifyLeafMatch(res, hash(1), e.size, e.index)
+}
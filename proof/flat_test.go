@@ -0,0 +1,165 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proof
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/transparency-dev/merkle/compact"
+)
+
+// flatProof is an inclusion proof in the form accepted by FlatProofSet.
+type flatProof struct {
+	index, size    uint64
+	leafHash, root []byte
+	proof          [][]byte
+}
+
+// allFlatProofs returns the inclusion proofs of all leaves in all trees of
+// size up to the given one.
+func allFlatProofs(t testing.TB, size uint64) []flatProof {
+	t.Helper()
+	nodes, roots := buildTree(t, size)
+	var res []flatProof
+	for size2 := uint64(1); size2 <= size; size2++ {
+		for index := uint64(0); index < size2; index++ {
+			n, err := Inclusion(index, size2)
+			if err != nil {
+				t.Fatalf("Inclusion: %v", err)
+			}
+			proof, err := n.Rehash(getHashes(nodes, n.IDs), hasher.HashChildren)
+			if err != nil {
+				t.Fatalf("Rehash: %v", err)
+			}
+			res = append(res, flatProof{
+				index:    index,
+				size:     size2,
+				leafHash: nodes[compact.NewNodeID(0, index)],
+				root:     roots[size2],
+				proof:    proof,
+			})
+		}
+	}
+	return res
+}
+
+func TestFlatProofSet(t *testing.T) {
+	proofs := allFlatProofs(t, 20)
+	var set FlatProofSet
+	if err := set.VerifyAll(hasher); err != nil {
+		t.Errorf("VerifyAll on empty set: %v", err)
+	}
+	for _, p := range proofs {
+		if err := set.Add(p.index, p.size, p.leafHash, p.root, bytes.Join(p.proof, nil)); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	if got, want := set.Len(), len(proofs); got != want {
+		t.Errorf("Len: got %d, want %d", got, want)
+	}
+	if err := set.VerifyAll(hasher); err != nil {
+		t.Fatalf("VerifyAll: %v", err)
+	}
+
+	// Break one of the proofs.
+	p := proofs[len(proofs)-1]
+	if err := set.Add(p.index, p.size, p.leafHash, sha256SomeHash, bytes.Join(p.proof, nil)); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	var mismatch RootMismatchError
+	if err := set.VerifyAll(hasher); !errors.As(err, &mismatch) {
+		t.Errorf("VerifyAll: got %v, want RootMismatchError", err)
+	} else if want := fmt.Sprintf("proof %d:", len(proofs)); !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("VerifyAll: got %q, want prefix %q", err, want)
+	}
+}
+
+func TestFlatProofSetErrors(t *testing.T) {
+	hash := sha256SomeHash
+	for _, tc := range []struct {
+		desc             string
+		index, size      uint64
+		leaf, root, hash []byte
+		wantAddErr       bool
+	}{
+		{desc: "empty-leaf", size: 1, root: hash, wantAddErr: true},
+		{desc: "short-root", size: 1, leaf: hash, root: hash[1:], wantAddErr: true},
+		{desc: "partial-hash", size: 2, leaf: hash, root: hash, hash: hash[1:], wantAddErr: true},
+		{desc: "index-beyond-size", index: 2, size: 2, leaf: hash, root: hash, hash: hash},
+		{desc: "short-proof", size: 3, leaf: hash, root: hash, hash: hash},
+		{desc: "non-empty-proof", size: 1, leaf: hash, root: hash, hash: hash},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			var set FlatProofSet
+			err := set.Add(tc.index, tc.size, tc.leaf, tc.root, tc.hash)
+			if got, want := err != nil, tc.wantAddErr; got != want {
+				t.Fatalf("Add: %v, want error %v", err, want)
+			} else if err != nil {
+				return
+			}
+			if err := set.VerifyAll(hasher); err == nil {
+				t.Error("VerifyAll succeeded")
+			}
+		})
+	}
+
+	var set FlatProofSet
+	if err := set.Add(0, 1, hash, hash, nil); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := set.Add(0, 1, hash[1:], hash[1:], nil); err == nil {
+		t.Error("Add accepted a hash of a different size")
+	}
+	short := FlatProofSet{}
+	if err := short.Add(0, 1, hash[1:], hash[1:], nil); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := short.VerifyAll(hasher); err == nil {
+		t.Error("VerifyAll succeeded with mismatching hash size")
+	}
+}
+
+func BenchmarkFlatProofSet(b *testing.B) {
+	proofs := allFlatProofs(b, 64)
+	b.Run("slices", func(b *testing.B) {
+		b.ReportAllocs()
+		for n := 0; n < b.N; n++ {
+			for _, p := range proofs {
+				if err := VerifyInclusion(hasher, p.index, p.size, p.leafHash, p.proof, p.root); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("flat", func(b *testing.B) {
+		var set FlatProofSet
+		for _, p := range proofs {
+			if err := set.Add(p.index, p.size, p.leafHash, p.root, bytes.Join(p.proof, nil)); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for n := 0; n < b.N; n++ {
+			if err := set.VerifyAll(hasher); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
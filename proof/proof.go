@@ -17,7 +17,9 @@ package proof
 
 import (
 	"fmt"
+	"math"
 	"math/bits"
+	"sort"
 
 	"github.com/transparency-dev/merkle/compact"
 )
@@ -42,6 +44,73 @@ type Nodes struct {
 	ephem compact.NodeID
 }
 
+// NewNodes returns the Nodes for a proof consisting of the given node IDs.
+// The IDs[begin:end] subslice contains the nodes that are used to re-create
+// the ephemeral node ephem, ordered from lower to upper levels. If begin ==
+// end, then the proof has no ephemeral node.
+//
+// This is useful for tools that build proofs with their own planning logic,
+// but want to use the Rehash method. Typical proofs should instead be created
+// with Inclusion and Consistency functions.
+//
+// NewNodes checks that the proof is structurally sound: the nodes cover
+// disjoint ranges of leaves, and the nodes under the ephemeral node tile a
+// contiguous range starting at its left border, from right to left. This
+// catches bogus or reordered lists, but does not check that the list is a
+// valid proof for a particular leaf or tree size.
+func NewNodes(ids []compact.NodeID, begin, end int, ephem compact.NodeID) (Nodes, error) {
+	if begin < 0 || end < begin || end > len(ids) {
+		return Nodes{}, fmt.Errorf("invalid ephemeral range [%d, %d) for %d nodes", begin, end, len(ids))
+	}
+	if err := checkDisjoint(ids); err != nil {
+		return Nodes{}, err
+	}
+	if begin == end {
+		return Nodes{IDs: ids, ephem: ephem}, nil
+	}
+	for _, id := range ids[begin:end] {
+		if id.Level > ephem.Level || id.Index>>(ephem.Level-id.Level) != ephem.Index {
+			return Nodes{}, fmt.Errorf("node %+v is not under the ephemeral node %+v", id, ephem)
+		}
+	}
+	// Rehash merges the nodes from right to left, so each node must cover the
+	// leaves immediately to the left of the previous one.
+	for i := begin + 1; i < end; i++ {
+		_, e := ids[i].Coverage()
+		if b, _ := ids[i-1].Coverage(); e != b {
+			return Nodes{}, fmt.Errorf("node %+v is not adjacent to %+v", ids[i], ids[i-1])
+		}
+	}
+	if got, want := ids[end-1].Index<<ids[end-1].Level, ephem.Index<<ephem.Level; got != want {
+		return Nodes{}, fmt.Errorf("nodes under the ephemeral node %+v start at %d, want %d", ephem, got, want)
+	}
+	return Nodes{IDs: ids, begin: begin, end: end, ephem: ephem}, nil
+}
+
+// checkDisjoint checks that the given nodes cover disjoint ranges of leaves.
+func checkDisjoint(ids []compact.NodeID) error {
+	for _, id := range ids {
+		// The end of the covered range must fit in uint64.
+		if id.Level >= 64 || id.Index >= math.MaxUint64>>id.Level {
+			return fmt.Errorf("node %+v is out of bounds", id)
+		}
+	}
+	sorted := make([]compact.NodeID, len(ids))
+	copy(sorted, ids)
+	sort.Slice(sorted, func(i, j int) bool {
+		b1, _ := sorted[i].Coverage()
+		b2, _ := sorted[j].Coverage()
+		return b1 < b2
+	})
+	for i := 1; i < len(sorted); i++ {
+		_, e := sorted[i-1].Coverage()
+		if b, _ := sorted[i].Coverage(); e > b {
+			return fmt.Errorf("nodes %+v and %+v overlap", sorted[i-1], sorted[i])
+		}
+	}
+	return nil
+}
+
 // Inclusion returns the information on how to fetch and construct an inclusion
 // proof for the given leaf index in a log Merkle tree of the given size. It
 // requires 0 <= index < size.
@@ -52,6 +121,93 @@ func Inclusion(index, size uint64) (Nodes, error) {
 	return nodes(index, 0, size).skipFirst(), nil
 }
 
+// InclusionDelta splits the nodes of the inclusion proof for the given leaf
+// index in the tree of size2 into the ones that need to be fetched, and the
+// ones whose hashes can be reused from the inclusion proof for the same leaf
+// in the tree of size1. Both lists are ordered like in Nodes.IDs of the size2
+// proof. Requires 0 <= index < size1 <= size2.
+//
+// If size1 < size2, the ephemeral node hash of the size1 proof is not reused,
+// because it commits to an incomplete subtree that the size2 proof does not
+// contain. If size1 == size2, the whole proof is reused.
+func InclusionDelta(index, size1, size2 uint64) ([]compact.NodeID, []compact.NodeID, error) {
+	if size2 < size1 {
+		return nil, nil, fmt.Errorf("size2 (%d) < size1 (%d)", size2, size1)
+	}
+	n1, err := Inclusion(index, size1)
+	if err != nil {
+		return nil, nil, err
+	}
+	n2, err := Inclusion(index, size2)
+	if err != nil {
+		return nil, nil, err
+	}
+	if size1 == size2 {
+		return nil, n2.IDs, nil
+	}
+	known := make(map[compact.NodeID]bool, len(n1.IDs))
+	for i, id := range n1.IDs {
+		if i < n1.begin || i >= n1.end {
+			known[id] = true
+		}
+	}
+	var fetch, reuse []compact.NodeID
+	for _, id := range n2.IDs {
+		if known[id] {
+			reuse = append(reuse, id)
+		} else {
+			fetch = append(fetch, id)
+		}
+	}
+	return fetch, reuse, nil
+}
+
+// InclusionAt returns the information on how to fetch and construct an
+// inclusion proof for the given node in a log Merkle tree of the given size.
+// The node is identified by its level and index, and must be the root of a
+// perfect subtree within the tree, i.e. (index+1) * 2^level <= size. For
+// level 0, this is equivalent to Inclusion.
+//
+// The resulting proof for the node hash can be checked with VerifyInclusion
+// as if it was a leaf at the given index in a tree of size
+// ((size-1) >> level) + 1.
+func InclusionAt(index uint64, level uint, size uint64) (Nodes, error) {
+	if level >= 64 || index >= size>>level {
+		return Nodes{}, fmt.Errorf("node (%d, %d) out of bounds for tree size %d", level, index, size)
+	}
+	return nodes(index, level, size).skipFirst(), nil
+}
+
+// RootCommitmentNodes returns the IDs of the perfect subtrees that the root
+// hash of a log Merkle tree of the given size commits to, ordered from left to
+// right. The root hash is computed by folding the hashes of these nodes from
+// right to left, e.g. see rfc6962.Hasher.RootFromSubtrees. The list is empty
+// if size is 0.
+func RootCommitmentNodes(size uint64) []compact.NodeID {
+	return compact.RangeNodes(0, size, nil)
+}
+
+// VerifyNodeIDs checks that the given list of node IDs is exactly the list of
+// nodes needed for an inclusion proof of the given leaf index in a log Merkle
+// tree of the given size, as returned by Inclusion. This can be used to detect
+// servers that fetch wrong nodes, even if the resulting proof happens to
+// verify.
+func VerifyNodeIDs(index, size uint64, got []compact.NodeID) error {
+	n, err := Inclusion(index, size)
+	if err != nil {
+		return err
+	}
+	if got, want := len(got), len(n.IDs); got != want {
+		return fmt.Errorf("got %d node IDs, want %d", got, want)
+	}
+	for i, id := range n.IDs {
+		if got[i] != id {
+			return fmt.Errorf("node %d: got ID %+v, want %+v", i, got[i], id)
+		}
+	}
+	return nil
+}
+
 // Consistency returns the information on how to fetch and construct a
 // consistency proof between the two given tree sizes of a log Merkle tree. It
 // requires 0 <= size1 <= size2.
@@ -79,6 +235,60 @@ func Consistency(size1, size2 uint64) (Nodes, error) {
 	return p, nil
 }
 
+// BuildInclusion returns the inclusion proof for the given leaf index in the
+// tree of the given size. The hashes of the nodes in the proof are fetched
+// with the getNode callback, which returns false if the node is unknown, and
+// the ephemeral node is then rehashed with hc, like in Rehash.
+func BuildInclusion(index, size uint64, getNode func(compact.NodeID) ([]byte, bool), hc func(left, right []byte) []byte) ([][]byte, error) {
+	n, err := Inclusion(index, size)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([][]byte, len(n.IDs))
+	for i, id := range n.IDs {
+		hash, ok := getNode(id)
+		if !ok {
+			return nil, fmt.Errorf("node %+v not found", id)
+		}
+		hashes[i] = hash
+	}
+	return n.Rehash(hashes, hc)
+}
+
+// ConsistencyFromRange returns the consistency proof between the size of the
+// given compact range, which must begin at index 0, and size2. The hashes of
+// the nodes that are in the compact range are taken from it, and the other
+// nodes are fetched with the getNode callback, which returns false if the node
+// is unknown. The hc parameter computes a node's hash based on hashes of its
+// children, like in Rehash.
+//
+// This allows an appender that keeps only the compact range of the tree, and
+// the nodes added since, to serve consistency proofs from this tree size.
+func ConsistencyFromRange(r *compact.Range, size2 uint64, getNode func(compact.NodeID) ([]byte, bool), hc func(left, right []byte) []byte) ([][]byte, error) {
+	if r.Begin() != 0 {
+		return nil, fmt.Errorf("range begins at %d, want 0", r.Begin())
+	}
+	n, err := Consistency(r.End(), size2)
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[compact.NodeID][]byte, len(r.Hashes()))
+	for i, id := range r.Spine() {
+		known[id] = r.Hashes()[i]
+	}
+	hashes := make([][]byte, len(n.IDs))
+	for i, id := range n.IDs {
+		hash, ok := known[id]
+		if !ok {
+			if hash, ok = getNode(id); !ok {
+				return nil, fmt.Errorf("node %+v not found", id)
+			}
+		}
+		hashes[i] = hash
+	}
+	return n.Rehash(hashes, hc)
+}
+
 // nodes returns the node IDs necessary to prove that the (level, index) node
 // is included in the Merkle tree of the given size.
 func nodes(index uint64, level uint, size uint64) Nodes {
@@ -147,12 +357,55 @@ func (n Nodes) Ephem() (compact.NodeID, int, int) {
 	return n.ephem, n.begin, n.end
 }
 
+// CoveredRanges returns the ranges of leaves covered by each element of the
+// proof, in the order of the hashes returned by Rehash. Each element covers
+// the leaves of the corresponding node, except the ephemeral node which covers
+// the union of the leaves of the nodes it is computed from.
+func (n Nodes) CoveredRanges() []compact.Interval {
+	res := make([]compact.Interval, 0, len(n.IDs))
+	for i := 0; i < len(n.IDs); i++ {
+		begin, end := n.IDs[i].Coverage()
+		if i >= n.begin && i < n.end {
+			// The block is ordered from right to left, so its last node is
+			// the leftmost one.
+			i = n.end - 1
+			begin, _ = n.IDs[i].Coverage()
+		}
+		res = append(res, compact.Interval{Begin: begin, End: end})
+	}
+	return res
+}
+
 // Rehash computes the proof based on the slice of node hashes corresponding to
 // their IDs in the n.IDs field. The slices must be of the same length. The hc
 // parameter computes a node's hash based on hashes of its children.
 //
 // Warning: The passed-in slice of hashes can be modified in-place.
 func (n Nodes) Rehash(h [][]byte, hc func(left, right []byte) []byte) ([][]byte, error) {
+	return n.rehash(h, hc, nil)
+}
+
+// RehashWithIntermediates computes the proof like Rehash, and additionally
+// returns the hashes of the composite nodes computed along the way, keyed by
+// their IDs. The last of these nodes is the ephemeral node (see Ephem), and
+// the others are the imperfect subtrees under it, each identified by the
+// parent of its left child in IDs. The map is empty if no rehashing is needed.
+//
+// Warning: The passed-in slice of hashes can be modified in-place.
+func (n Nodes) RehashWithIntermediates(h [][]byte, hc func(left, right []byte) []byte) ([][]byte, compact.NodeMap, error) {
+	composite := make(compact.NodeMap)
+	proof, err := n.rehash(h, hc, func(id compact.NodeID, hash []byte) {
+		composite[id] = hash
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return proof, composite, nil
+}
+
+// rehash implements Rehash, and reports the computed composite nodes through
+// the visitor function (if non-nil).
+func (n Nodes) rehash(h [][]byte, hc func(left, right []byte) []byte, visitor compact.VisitFn) ([][]byte, error) {
 	if got, want := len(h), len(n.IDs); got != want {
 		return nil, fmt.Errorf("got %d hashes but expected %d", got, want)
 	}
@@ -166,6 +419,13 @@ func (n Nodes) Rehash(h [][]byte, hc func(left, right []byte) []byte) ([][]byte,
 			// Scan the block of node hashes that need rehashing.
 			for i++; i < n.end; i++ {
 				hash = hc(h[i], hash)
+				if visitor == nil {
+					continue
+				} else if i+1 < n.end {
+					visitor(n.IDs[i].Parent(), hash)
+				} else {
+					visitor(n.ephem, hash)
+				}
 			}
 			i--
 		}
@@ -15,7 +15,10 @@
 package proof
 
 import (
+	"bytes"
 	"fmt"
+	"math/bits"
+	"sort"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -130,6 +133,362 @@ func TestInclusion(t *testing.T) {
 	}
 }
 
+func TestInclusionAt(t *testing.T) {
+	const size = uint64(40)
+	nodes, roots := buildTree(t, size)
+	for sz := uint64(1); sz <= size; sz++ {
+		for level := uint(0); sz>>level != 0; level++ {
+			for index := uint64(0); index < sz>>level; index++ {
+				n, err := InclusionAt(index, level, sz)
+				if err != nil {
+					t.Fatalf("InclusionAt(%d, %d, %d): %v", index, level, sz, err)
+				}
+				proof, err := n.Rehash(getHashes(nodes, n.IDs), hasher.HashChildren)
+				if err != nil {
+					t.Fatalf("Rehash: %v", err)
+				}
+				hash := nodes[compact.NewNodeID(level, index)]
+				if err := VerifyInclusion(hasher, index, (sz-1)>>level+1, hash, proof, roots[sz]); err != nil {
+					t.Errorf("InclusionAt(%d, %d, %d): VerifyInclusion: %v", index, level, sz, err)
+				}
+			}
+		}
+	}
+}
+
+func TestInclusionAtConsistency(t *testing.T) {
+	for size2 := uint64(1); size2 <= 100; size2++ {
+		for size1 := uint64(1); size1 < size2; size1++ {
+			level := uint(bits.TrailingZeros64(size1))
+			index := (size1 - 1) >> level
+			n, err := InclusionAt(index, level, size2)
+			if err != nil {
+				t.Fatalf("InclusionAt: %v", err)
+			}
+			c, err := Consistency(size1, size2)
+			if err != nil {
+				t.Fatalf("Consistency: %v", err)
+			}
+			// The consistency proof is the inclusion proof of the biggest perfect
+			// subtree ending at size1, preceded by this subtree's root if needed.
+			want := c.IDs
+			if index != 0 {
+				want = want[1:]
+			}
+			if diff := cmp.Diff(want, n.IDs); diff != "" {
+				t.Errorf("InclusionAt(%d, %d, %d): diff (-want +got)\n%s", index, level, size2, diff)
+			}
+		}
+	}
+}
+
+func TestInclusionAtErrors(t *testing.T) {
+	for _, tc := range []struct {
+		index uint64
+		level uint
+		size  uint64
+	}{
+		{index: 0, level: 0, size: 0},
+		{index: 7, level: 0, size: 7},
+		{index: 0, level: 3, size: 7},
+		{index: 1, level: 2, size: 7},
+		{index: 0, level: 64, size: 1<<64 - 1},
+		{index: 1<<63 - 1, level: 1, size: 1<<64 - 1},
+	} {
+		t.Run(fmt.Sprintf("%d:%d:%d", tc.index, tc.level, tc.size), func(t *testing.T) {
+			if _, err := InclusionAt(tc.index, tc.level, tc.size); err == nil {
+				t.Error("InclusionAt succeeded unexpectedly")
+			}
+		})
+	}
+}
+
+func TestRehashWithIntermediates(t *testing.T) {
+	const size = uint64(40)
+	nodes, _ := buildTree(t, size)
+	for sz := uint64(1); sz <= size; sz++ {
+		for index := uint64(0); index < sz; index++ {
+			n, err := Inclusion(index, sz)
+			if err != nil {
+				t.Fatalf("Inclusion: %v", err)
+			}
+			want, err := n.Rehash(getHashes(nodes, n.IDs), hasher.HashChildren)
+			if err != nil {
+				t.Fatalf("Rehash: %v", err)
+			}
+			proof, composite, err := n.RehashWithIntermediates(getHashes(nodes, n.IDs), hasher.HashChildren)
+			if err != nil {
+				t.Fatalf("RehashWithIntermediates: %v", err)
+			}
+			if diff := cmp.Diff(want, proof); diff != "" {
+				t.Errorf("RehashWithIntermediates(%d, %d): proof diff (-want +got)\n%s", index, sz, diff)
+			}
+
+			ephem, begin, end := n.Ephem()
+			if got, want := len(composite), max(end-begin-1, 0); got != want {
+				t.Errorf("RehashWithIntermediates(%d, %d): got %d composite nodes, want %d", index, sz, got, want)
+			}
+			if end-begin > 1 && !bytes.Equal(composite[ephem], proof[begin]) {
+				t.Errorf("RehashWithIntermediates(%d, %d): ephemeral node hash mismatch", index, sz)
+			}
+			// Each composite node hash is the root of the compact range of the
+			// leaves that it covers in the tree.
+			for id, hash := range composite {
+				b, e := id.Coverage()
+				hashes := getHashes(nodes, compact.RangeNodes(b, min(e, sz), nil))
+				want := hashes[len(hashes)-1]
+				for i := len(hashes) - 2; i >= 0; i-- {
+					want = hasher.HashChildren(hashes[i], want)
+				}
+				if !bytes.Equal(hash, want) {
+					t.Errorf("RehashWithIntermediates(%d, %d): node %+v: got %x, want %x", index, sz, id, hash, want)
+				}
+			}
+		}
+	}
+}
+
+func TestInclusionSiblingPath(t *testing.T) {
+	for size := uint64(1); size <= 100; size++ {
+		for index := uint64(0); index < size; index++ {
+			n, err := Inclusion(index, size)
+			if err != nil {
+				t.Fatalf("Inclusion: %v", err)
+			}
+			path := compact.SiblingPath(index, size)
+			if len(path) > len(n.IDs) {
+				t.Fatalf("SiblingPath(%d, %d): got %d IDs, want <= %d", index, size, len(path), len(n.IDs))
+			}
+			if diff := cmp.Diff(n.IDs[:len(path)], path); diff != "" {
+				t.Errorf("SiblingPath(%d, %d): diff(-want +got):\n%s", index, size, diff)
+			}
+		}
+	}
+}
+
+func TestRootCommitmentNodes(t *testing.T) {
+	id := compact.NewNodeID
+	// The tree of size 13 consists of perfect subtrees of sizes 8, 4 and 1.
+	want := []compact.NodeID{id(3, 0), id(2, 2), id(0, 12)}
+	if diff := cmp.Diff(want, RootCommitmentNodes(13)); diff != "" {
+		t.Errorf("RootCommitmentNodes(13): diff (-want +got)\n%s", diff)
+	}
+	if got := RootCommitmentNodes(0); len(got) != 0 {
+		t.Errorf("RootCommitmentNodes(0): got %+v, want empty", got)
+	}
+
+	nodes, roots := buildTree(t, 13)
+	hashes := getHashes(nodes, RootCommitmentNodes(13))
+	if got, want := rfc6962.DefaultHasher.RootFromSubtrees(hashes), roots[13]; !bytes.Equal(got, want) {
+		t.Errorf("root mismatch: got %x, want %x", got, want)
+	}
+}
+
+func TestCoveredRanges(t *testing.T) {
+	iv := func(begin, end uint64) compact.Interval {
+		return compact.Interval{Begin: begin, End: end}
+	}
+	for _, tc := range []struct {
+		index, size uint64
+		want        []compact.Interval
+	}{
+		{index: 0, size: 1, want: []compact.Interval{}},
+		{index: 1, size: 7, want: []compact.Interval{iv(0, 1), iv(2, 4), iv(4, 7)}}, // a h l
+		{index: 4, size: 7, want: []compact.Interval{iv(5, 6), iv(6, 7), iv(0, 4)}}, // f j k
+		{index: 6, size: 7, want: []compact.Interval{iv(4, 6), iv(0, 4)}},           // i k
+		{index: 3, size: 5, want: []compact.Interval{iv(2, 3), iv(0, 2), iv(4, 5)}}, // c g e
+	} {
+		t.Run(fmt.Sprintf("%d:%d", tc.index, tc.size), func(t *testing.T) {
+			n, err := Inclusion(tc.index, tc.size)
+			if err != nil {
+				t.Fatalf("Inclusion: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, n.CoveredRanges()); diff != "" {
+				t.Errorf("CoveredRanges: diff (-want +got)\n%s", diff)
+			}
+		})
+	}
+
+	// The covered ranges of an inclusion proof, and the leaf itself, must tile
+	// the whole tree.
+	for size := uint64(1); size <= 100; size++ {
+		for index := uint64(0); index < size; index++ {
+			n, err := Inclusion(index, size)
+			if err != nil {
+				t.Fatalf("Inclusion: %v", err)
+			}
+			ranges := append(n.CoveredRanges(), iv(index, index+1))
+			if got, want := len(ranges), inclusionProofSize(index, size)+1; got != want {
+				t.Fatalf("CoveredRanges(%d, %d): got %d ranges, want %d", index, size, got, want)
+			}
+			sort.Slice(ranges, func(i, j int) bool { return ranges[i].Begin < ranges[j].Begin })
+			var end uint64
+			for _, r := range ranges {
+				if r.Begin != end || r.End <= r.Begin {
+					t.Fatalf("CoveredRanges(%d, %d): ranges %+v don't tile the tree", index, size, ranges)
+				}
+				end = r.End
+			}
+			if end != size {
+				t.Fatalf("CoveredRanges(%d, %d): ranges %+v end at %d", index, size, ranges, end)
+			}
+		}
+	}
+}
+
+func TestInclusionDelta(t *testing.T) {
+	id := compact.NewNodeID
+	for _, tc := range []struct {
+		index, size1, size2 uint64
+		wantFetch           []compact.NodeID
+		wantReuse           []compact.NodeID
+		wantErr             bool
+	}{
+		{
+			index: 5, size1: 8, size2: 13,
+			wantFetch: []compact.NodeID{id(0, 12), id(2, 2)},
+			wantReuse: []compact.NodeID{id(0, 4), id(1, 3), id(2, 0)},
+		},
+		{
+			// The ephemeral node of the size1 proof is not reused.
+			index: 1, size1: 5, size2: 8,
+			wantFetch: []compact.NodeID{id(2, 1)},
+			wantReuse: []compact.NodeID{id(0, 0), id(1, 1)},
+		},
+		{
+			index: 2, size1: 7, size2: 7,
+			wantReuse: []compact.NodeID{id(0, 3), id(1, 0), id(0, 6), id(1, 2)},
+		},
+		{index: 5, size1: 5, size2: 8, wantErr: true},
+		{index: 1, size1: 8, size2: 5, wantErr: true},
+	} {
+		t.Run(fmt.Sprintf("%d:%d:%d", tc.index, tc.size1, tc.size2), func(t *testing.T) {
+			fetch, reuse, err := InclusionDelta(tc.index, tc.size1, tc.size2)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("InclusionDelta: %v, want error %v", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.wantFetch, fetch); diff != "" {
+				t.Errorf("fetch: diff (-want +got)\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantReuse, reuse); diff != "" {
+				t.Errorf("reuse: diff (-want +got)\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestBuildInclusion(t *testing.T) {
+	const size = uint64(40)
+	nodes, roots := buildTree(t, size)
+	getNode := func(id compact.NodeID) ([]byte, bool) {
+		hash, ok := nodes[id]
+		return hash, ok
+	}
+	for size2 := uint64(1); size2 <= size; size2++ {
+		for index := uint64(0); index < size2; index++ {
+			got, err := BuildInclusion(index, size2, getNode, hasher.HashChildren)
+			if err != nil {
+				t.Fatalf("BuildInclusion(%d, %d): %v", index, size2, err)
+			}
+			leaf := nodes[compact.NewNodeID(0, index)]
+			if err := VerifyInclusion(hasher, index, size2, leaf, got, roots[size2]); err != nil {
+				t.Errorf("BuildInclusion(%d, %d): VerifyInclusion: %v", index, size2, err)
+			}
+		}
+	}
+
+	noNodes := func(compact.NodeID) ([]byte, bool) { return nil, false }
+	if _, err := BuildInclusion(3, 7, noNodes, hasher.HashChildren); err == nil {
+		t.Error("BuildInclusion succeeded with missing nodes")
+	}
+	if _, err := BuildInclusion(7, 7, getNode, hasher.HashChildren); err == nil {
+		t.Error("BuildInclusion succeeded with index >= size")
+	}
+}
+
+func TestConsistencyFromRange(t *testing.T) {
+	const size = uint64(40)
+	nodes, roots := buildTree(t, size)
+	rf := &compact.RangeFactory{Hash: hasher.HashChildren}
+	for size1 := uint64(0); size1 <= size; size1++ {
+		r, err := rf.NewRangeFromNodes(0, size1, nodes)
+		if err != nil {
+			t.Fatalf("NewRange: %v", err)
+		}
+		// The store only knows the nodes that were added after size1.
+		getNode := func(id compact.NodeID) ([]byte, bool) {
+			if _, end := id.Coverage(); end <= size1 {
+				return nil, false
+			}
+			hash, ok := nodes[id]
+			return hash, ok
+		}
+		for size2 := size1; size2 <= size; size2++ {
+			got, err := ConsistencyFromRange(r, size2, getNode, hasher.HashChildren)
+			if err != nil {
+				t.Fatalf("ConsistencyFromRange(%d, %d): %v", size1, size2, err)
+			}
+			n, err := Consistency(size1, size2)
+			if err != nil {
+				t.Fatalf("Consistency: %v", err)
+			}
+			want, err := n.Rehash(getHashes(nodes, n.IDs), hasher.HashChildren)
+			if err != nil {
+				t.Fatalf("Rehash: %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("ConsistencyFromRange(%d, %d): diff (-want +got)\n%s", size1, size2, diff)
+			}
+			if size1 != 0 {
+				if err := VerifyConsistency(hasher, size1, size2, got, roots[size1], roots[size2]); err != nil {
+					t.Errorf("ConsistencyFromRange(%d, %d): VerifyConsistency: %v", size1, size2, err)
+				}
+			}
+		}
+	}
+
+	r, err := rf.NewRangeFromNodes(0, 5, nodes)
+	if err != nil {
+		t.Fatalf("NewRange: %v", err)
+	}
+	noNodes := func(compact.NodeID) ([]byte, bool) { return nil, false }
+	if _, err := ConsistencyFromRange(r, 7, noNodes, hasher.HashChildren); err == nil {
+		t.Error("ConsistencyFromRange succeeded with missing nodes")
+	}
+	if _, err := ConsistencyFromRange(r, 4, noNodes, hasher.HashChildren); err == nil {
+		t.Error("ConsistencyFromRange succeeded with size2 < size1")
+	}
+	if _, err := ConsistencyFromRange(rf.NewEmptyRange(3), 7, noNodes, hasher.HashChildren); err == nil {
+		t.Error("ConsistencyFromRange succeeded with range not beginning at 0")
+	}
+}
+
+func TestVerifyNodeIDs(t *testing.T) {
+	id := compact.NewNodeID
+	for _, tc := range []struct {
+		desc    string
+		index   uint64
+		size    uint64
+		ids     []compact.NodeID
+		wantErr bool
+	}{
+		{desc: "ok", index: 2, size: 7, ids: []compact.NodeID{id(0, 3), id(1, 0), id(0, 6), id(1, 2)}},
+		{desc: "ok-single", index: 0, size: 1, ids: []compact.NodeID{}},
+		{desc: "wrong-id", index: 2, size: 7, ids: []compact.NodeID{id(0, 2), id(1, 0), id(0, 6), id(1, 2)}, wantErr: true},
+		{desc: "swapped", index: 2, size: 7, ids: []compact.NodeID{id(0, 3), id(1, 0), id(1, 2), id(0, 6)}, wantErr: true},
+		{desc: "too-short", index: 2, size: 7, ids: []compact.NodeID{id(0, 3), id(1, 0), id(0, 6)}, wantErr: true},
+		{desc: "too-long", index: 6, size: 7, ids: []compact.NodeID{id(1, 2), id(2, 0), id(3, 1)}, wantErr: true},
+		{desc: "bad-index", index: 7, size: 7, wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := VerifyNodeIDs(tc.index, tc.size, tc.ids)
+			if got, want := err != nil, tc.wantErr; got != want {
+				t.Errorf("VerifyNodeIDs: %v, wantErr %v", err, want)
+			}
+		})
+	}
+}
+
 // TestConsistency contains consistency proof tests. For reference, consider
 // the following example:
 //
@@ -305,6 +664,186 @@ func TestEphem(t *testing.T) {
 	}
 }
 
+// TestLargeTrees checks proof shapes for production-sized trees near 2^40,
+// where bugs in the bits.Len64 and shift arithmetic at high levels would show.
+func TestLargeTrees(t *testing.T) {
+	id := compact.NewNodeID
+	const big = uint64(1) << 40
+	// rehashLen returns the number of hashes in the proof after rehashing.
+	rehashLen := func(n Nodes) int {
+		t.Helper()
+		hashes := make([][]byte, len(n.IDs))
+		for i := range hashes {
+			hashes[i] = sha256SomeHash
+		}
+		proof, err := n.Rehash(hashes, hasher.HashChildren)
+		if err != nil {
+			t.Fatalf("Rehash: %v", err)
+		}
+		return len(proof)
+	}
+
+	for _, tc := range []struct {
+		index, size uint64
+		wantEphem   compact.NodeID
+		wantLen     int
+	}{
+		{index: 0, size: big, wantEphem: id(40, 1), wantLen: 40},
+		{index: big - 1, size: big, wantEphem: id(40, 1), wantLen: 40},
+		{index: 0, size: big + 1, wantEphem: id(40, 1), wantLen: 41},
+		{index: big - 1, size: big + 1, wantEphem: id(40, 1), wantLen: 41},
+		{index: big, size: big + 1, wantEphem: id(0, big+1), wantLen: 1},
+		{index: big, size: big + big/2 + 1, wantEphem: id(39, 3), wantLen: 41},
+		{index: 12345, size: big + big/2 + 1, wantEphem: id(40, 1), wantLen: 41},
+		{index: big - 2, size: 2*big - 1, wantEphem: id(40, 1), wantLen: 41},
+	} {
+		t.Run(fmt.Sprintf("inclusion:%d:%d", tc.index, tc.size), func(t *testing.T) {
+			n, err := Inclusion(tc.index, tc.size)
+			if err != nil {
+				t.Fatalf("Inclusion: %v", err)
+			}
+			if got, _, _ := n.Ephem(); got != tc.wantEphem {
+				t.Errorf("Ephem: got %+v, want %+v", got, tc.wantEphem)
+			}
+			if got := rehashLen(n); got != tc.wantLen {
+				t.Errorf("proof length: got %d, want %d", got, tc.wantLen)
+			}
+			if got := inclusionProofSize(tc.index, tc.size); got != tc.wantLen {
+				t.Errorf("inclusionProofSize: got %d, want %d", got, tc.wantLen)
+			}
+		})
+	}
+
+	for _, tc := range []struct {
+		size1, size2 uint64
+		wantLen      int
+	}{
+		{size1: big, size2: big + 1, wantLen: 1},
+		{size1: big, size2: big + big/2 + 1, wantLen: 1},
+		{size1: big, size2: 2 * big, wantLen: 1},
+		{size1: big - 1, size2: big, wantLen: 41},
+		{size1: big - 1, size2: big + 1, wantLen: 42},
+		{size1: big + 1, size2: 2 * big, wantLen: 42},
+		{size1: big + 1, size2: big + 2, wantLen: 3},
+	} {
+		t.Run(fmt.Sprintf("consistency:%d:%d", tc.size1, tc.size2), func(t *testing.T) {
+			n, err := Consistency(tc.size1, tc.size2)
+			if err != nil {
+				t.Fatalf("Consistency: %v", err)
+			}
+			if got := rehashLen(n); got != tc.wantLen {
+				t.Errorf("proof length: got %d, want %d", got, tc.wantLen)
+			}
+			if got := ConsistencyProofSize(tc.size1, tc.size2); got != tc.wantLen {
+				t.Errorf("ConsistencyProofSize: got %d, want %d", got, tc.wantLen)
+			}
+		})
+	}
+}
+
+func TestNewNodes(t *testing.T) {
+	th := rfc6962.DefaultHasher
+	for _, tc := range []struct {
+		index, size uint64
+	}{
+		{index: 0, size: 1}, {index: 3, size: 8}, {index: 2, size: 7},
+		{index: 10, size: 15}, {index: 81, size: 95}, {index: 123, size: 1025},
+	} {
+		t.Run(fmt.Sprintf("%d:%d", tc.index, tc.size), func(t *testing.T) {
+			want := inclusion(t, tc.index, tc.size)
+			ephem, begin, end := want.Ephem()
+			got, err := NewNodes(want.IDs, begin, end, ephem)
+			if err != nil {
+				t.Fatalf("NewNodes: %v", err)
+			}
+			if diff := cmp.Diff(want, got, cmp.AllowUnexported(Nodes{})); diff != "" {
+				t.Errorf("nodes mismatch:\n%v", diff)
+			}
+
+			hashes := make([][]byte, len(want.IDs))
+			for i := range hashes {
+				hashes[i] = th.HashLeaf([]byte(fmt.Sprintf("hash %d", i)))
+			}
+			wantProof, err := want.Rehash(append([][]byte{}, hashes...), th.HashChildren)
+			if err != nil {
+				t.Fatalf("Rehash: %v", err)
+			}
+			gotProof, err := got.Rehash(append([][]byte{}, hashes...), th.HashChildren)
+			if err != nil {
+				t.Fatalf("Rehash: %v", err)
+			}
+			if !cmp.Equal(gotProof, wantProof) {
+				t.Errorf("proofs mismatch:\ngot: %x\nwant: %x", gotProof, wantProof)
+			}
+		})
+	}
+}
+
+func TestNewNodesErrors(t *testing.T) {
+	id := compact.NewNodeID
+	ids := []compact.NodeID{id(0, 0), id(1, 1), id(0, 6), id(1, 2)} // a h j i
+	for _, tc := range []struct {
+		desc       string
+		ids        []compact.NodeID
+		begin, end int
+		ephem      compact.NodeID
+	}{
+		{desc: "negative-begin", begin: -1, end: 2, ephem: id(2, 1)},
+		{desc: "end-before-begin", begin: 3, end: 2, ephem: id(2, 1)},
+		{desc: "end-out-of-bounds", begin: 2, end: 5, ephem: id(2, 1)},
+		{desc: "not-under-ephem", begin: 1, end: 3, ephem: id(2, 1)},
+		{desc: "ephem-too-low", begin: 2, end: 4, ephem: id(0, 6)},
+		{
+			desc: "swapped-block", begin: 2, end: 4, ephem: id(2, 1),
+			ids: []compact.NodeID{id(0, 0), id(1, 1), id(1, 2), id(0, 6)}, // a h i j
+		},
+		{
+			desc: "gap-in-block", begin: 2, end: 4, ephem: id(2, 1),
+			ids: []compact.NodeID{id(0, 0), id(1, 1), id(0, 7), id(1, 2)}, // a h leaf#7 i
+		},
+		{
+			desc: "block-not-at-border", begin: 2, end: 3, ephem: id(2, 1),
+			ids: []compact.NodeID{id(0, 0), id(1, 1), id(0, 6)}, // a h j
+		},
+		{
+			desc: "overlap", begin: 0, end: 0,
+			ids: []compact.NodeID{id(0, 0), id(1, 1), id(0, 2)}, // a h c
+		},
+		{
+			desc: "duplicate", begin: 0, end: 0,
+			ids: []compact.NodeID{id(0, 1), id(1, 1), id(0, 1)}, // b h b
+		},
+		{
+			desc: "out-of-bounds", begin: 0, end: 0,
+			ids: []compact.NodeID{id(63, 1)},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if tc.ids == nil {
+				tc.ids = ids
+			}
+			if _, err := NewNodes(tc.ids, tc.begin, tc.end, tc.ephem); err == nil {
+				t.Error("NewNodes succeeded unexpectedly")
+			}
+		})
+	}
+}
+
+func TestNewNodesConsistency(t *testing.T) {
+	for size2 := uint64(1); size2 <= 100; size2++ {
+		for size1 := uint64(0); size1 <= size2; size1++ {
+			want, err := Consistency(size1, size2)
+			if err != nil {
+				t.Fatalf("Consistency: %v", err)
+			}
+			ephem, begin, end := want.Ephem()
+			if _, err := NewNodes(want.IDs, begin, end, ephem); err != nil {
+				t.Errorf("NewNodes(Consistency(%d, %d)): %v", size1, size2, err)
+			}
+		}
+	}
+}
+
 func TestRehash(t *testing.T) {
 	th := rfc6962.DefaultHasher
 	h := [][]byte{
@@ -0,0 +1,70 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proof
+
+import (
+	"fmt"
+
+	"github.com/transparency-dev/merkle/compact"
+)
+
+// RootBuilder computes the root hash of a tree from its leaf hashes, which can
+// be provided in any order. The leaves that extend the contiguous prefix of
+// the tree are folded into a compact range as soon as possible, so only the
+// out-of-order leaves are buffered.
+type RootBuilder struct {
+	size    uint64
+	rng     *compact.Range
+	pending map[uint64][]byte
+}
+
+// NewRootBuilder returns a RootBuilder for the tree of the given size. The hc
+// parameter computes a node's hash based on hashes of its children.
+func NewRootBuilder(size uint64, hc func(left, right []byte) []byte) *RootBuilder {
+	rf := &compact.RangeFactory{Hash: hc}
+	return &RootBuilder{size: size, rng: rf.NewEmptyRange(0), pending: make(map[uint64][]byte)}
+}
+
+// Set provides the hash of the leaf with the given index. Returns an error if
+// the index is out of bounds, or the leaf has already been set.
+func (b *RootBuilder) Set(index uint64, hash []byte) error {
+	if index >= b.size {
+		return fmt.Errorf("index %d out of bounds for tree size %d", index, b.size)
+	}
+	if _, ok := b.pending[index]; ok || index < b.rng.End() {
+		return fmt.Errorf("leaf %d already set", index)
+	}
+	b.pending[index] = hash
+	for {
+		next, ok := b.pending[b.rng.End()]
+		if !ok {
+			return nil
+		}
+		delete(b.pending, b.rng.End())
+		if err := b.rng.Append(next, nil); err != nil {
+			return err
+		}
+	}
+}
+
+// Root returns the root hash of the tree. Returns an error if not all the
+// leaves have been set. Like compact.Range.GetRootHash, returns nil for an
+// empty tree.
+func (b *RootBuilder) Root() ([]byte, error) {
+	if end := b.rng.End(); end != b.size {
+		return nil, fmt.Errorf("leaf %d is missing", end)
+	}
+	return b.rng.GetRootHash(nil)
+}
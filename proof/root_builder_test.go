@@ -0,0 +1,68 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proof
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/transparency-dev/merkle/compact"
+)
+
+func TestRootBuilder(t *testing.T) {
+	const size = uint64(100)
+	nodes, roots := buildTree(t, size)
+	rnd := rand.New(rand.NewPCG(1, 2))
+
+	for _, size := range []uint64{1, 2, 7, 64, size} {
+		b := NewRootBuilder(size, hasher.HashChildren)
+		perm := rnd.Perm(int(size))
+		for i, index := range perm {
+			if i == len(perm)-1 {
+				if _, err := b.Root(); err == nil {
+					t.Errorf("size %d: Root succeeded with a missing leaf", size)
+				}
+			}
+			if err := b.Set(uint64(index), nodes[compact.NewNodeID(0, uint64(index))]); err != nil {
+				t.Fatalf("size %d: Set(%d): %v", size, index, err)
+			}
+		}
+		root, err := b.Root()
+		if err != nil {
+			t.Fatalf("size %d: Root: %v", size, err)
+		}
+		if want := roots[size]; !bytes.Equal(root, want) {
+			t.Errorf("size %d: Root: got %x, want %x", size, root, want)
+		}
+	}
+
+	b := NewRootBuilder(4, hasher.HashChildren)
+	if err := b.Set(4, sha256SomeHash); err == nil {
+		t.Error("Set succeeded with index out of bounds")
+	}
+	for _, index := range []uint64{2, 0} {
+		if err := b.Set(index, sha256SomeHash); err != nil {
+			t.Fatalf("Set(%d): %v", index, err)
+		}
+		if err := b.Set(index, sha256SomeHash); err == nil {
+			t.Errorf("Set(%d) succeeded twice", index)
+		}
+	}
+
+	if root, err := NewRootBuilder(0, hasher.HashChildren).Root(); err != nil || root != nil {
+		t.Errorf("Root of empty tree: got %x, %v; want nil, nil", root, err)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proof
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/transparency-dev/merkle"
+)
+
+// DetectSplitView reports whether two root hashes observed for the same tree
+// size differ. A log that serves different roots for the same size to
+// different clients presents a split view, which is a provable misbehaviour.
+// The size is only passed for readability at the call site.
+func DetectSplitView(size uint64, rootA, rootB []byte) bool {
+	return !bytes.Equal(rootA, rootB)
+}
+
+// DetectSplitViewConsistency reports whether the trees of size1 and size2
+// with the given root hashes are not on the same lineage, as witnessed by the
+// given consistency proof between them. If size1 == size2, this is equivalent
+// to DetectSplitView, and the proof must be empty.
+//
+// Returns true if the proof is well-formed but does not connect the two roots.
+// Returns an error if the proof could not be checked at all, e.g. because the
+// sizes are out of order or the proof has a wrong number of hashes.
+//
+// Note that a consistency proof is served by the log itself, so a true result
+// means that the log either forked or served an invalid proof. Either way it
+// is misbehaving, but only two roots for the same size are standalone evidence
+// of a split view.
+func DetectSplitViewConsistency(hasher merkle.LogHasher, size1, size2 uint64, proof [][]byte, root1, root2 []byte) (bool, error) {
+	if size1 == size2 && len(proof) == 0 {
+		return DetectSplitView(size1, root1, root2), nil
+	}
+	err := VerifyConsistency(hasher, size1, size2, proof, root1, root2)
+	var mismatch RootMismatchError
+	if errors.As(err, &mismatch) {
+		return true, nil
+	} else if err != nil {
+		return false, err
+	}
+	return false, nil
+}
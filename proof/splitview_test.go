@@ -0,0 +1,71 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proof
+
+import "testing"
+
+func TestDetectSplitView(t *testing.T) {
+	_, roots := buildTree(t, 8)
+	if DetectSplitView(5, roots[5], roots[5]) {
+		t.Error("DetectSplitView: same roots reported as split view")
+	}
+	if !DetectSplitView(5, roots[5], sha256SomeHash) {
+		t.Error("DetectSplitView: different roots not reported as split view")
+	}
+}
+
+func TestDetectSplitViewConsistency(t *testing.T) {
+	const size = uint64(16)
+	nodes, roots := buildTree(t, size)
+	proof := func(size1, size2 uint64) [][]byte {
+		n, err := Consistency(size1, size2)
+		if err != nil {
+			t.Fatalf("Consistency: %v", err)
+		}
+		p, err := n.Rehash(getHashes(nodes, n.IDs), hasher.HashChildren)
+		if err != nil {
+			t.Fatalf("Rehash: %v", err)
+		}
+		return p
+	}
+
+	for _, tc := range []struct {
+		desc         string
+		size1, size2 uint64
+		proof        [][]byte
+		root1, root2 []byte
+		want         bool
+		wantErr      bool
+	}{
+		{desc: "same-size", size1: 5, size2: 5, root1: roots[5], root2: roots[5]},
+		{desc: "same-size-diverged", size1: 5, size2: 5, root1: roots[5], root2: sha256SomeHash, want: true},
+		{desc: "consistent", size1: 5, size2: 13, proof: proof(5, 13), root1: roots[5], root2: roots[13]},
+		{desc: "diverged-root1", size1: 5, size2: 13, proof: proof(5, 13), root1: sha256SomeHash, root2: roots[13], want: true},
+		{desc: "diverged-root2", size1: 5, size2: 13, proof: proof(5, 13), root1: roots[5], root2: roots[12], want: true},
+		{desc: "wrong-proof-size", size1: 5, size2: 13, proof: proof(5, 12)[1:], root1: roots[5], root2: roots[13], wantErr: true},
+		{desc: "out-of-order", size1: 13, size2: 5, proof: proof(5, 13), root1: roots[13], root2: roots[5], wantErr: true},
+		{desc: "same-size-with-proof", size1: 5, size2: 5, proof: [][]byte{sha256SomeHash}, root1: roots[5], root2: roots[5], wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := DetectSplitViewConsistency(hasher, tc.size1, tc.size2, tc.proof, tc.root1, tc.root2)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("DetectSplitViewConsistency: %v, want error %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("DetectSplitViewConsistency: got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proof
+
+import (
+	"fmt"
+	"math"
+
+	"golang.org/x/mod/sumdb/tlog"
+)
+
+// ToSumDB converts the inclusion proof for the given leaf index in the tree of
+// the given size to the format used by Go checksum database, see
+// golang.org/x/mod/sumdb/tlog. The proof must be for a tree using the RFC 6962
+// SHA-256 hasher, like the checksum database does.
+func ToSumDB(index, size uint64, proof [][]byte) (tlog.RecordProof, error) {
+	if size > math.MaxInt64 {
+		return nil, fmt.Errorf("tree size %d too big", size)
+	} else if index >= size {
+		return nil, fmt.Errorf("index %d out of bounds for tree size %d", index, size)
+	}
+	if got, want := len(proof), inclusionProofSize(index, size); got != want {
+		return nil, fmt.Errorf("wrong proof size %d, want %d", got, want)
+	}
+	return toSumDBHashes(proof)
+}
+
+// FromSumDB converts the inclusion proof from the format used by Go checksum
+// database.
+func FromSumDB(p tlog.RecordProof) [][]byte {
+	return fromSumDBHashes(p)
+}
+
+// ToSumDBTree converts the consistency proof between the given tree sizes to
+// the format used by Go checksum database, see golang.org/x/mod/sumdb/tlog.
+// The proof must be for a tree using the RFC 6962 SHA-256 hasher. Requires
+// 0 < size1 <= size2.
+func ToSumDBTree(size1, size2 uint64, proof [][]byte) (tlog.TreeProof, error) {
+	if size2 > math.MaxInt64 {
+		return nil, fmt.Errorf("tree size %d too big", size2)
+	}
+	if got, want := len(proof), ConsistencyProofSize(size1, size2); want < 0 {
+		return nil, fmt.Errorf("invalid tree sizes %d and %d", size1, size2)
+	} else if got != want {
+		return nil, fmt.Errorf("wrong proof size %d, want %d", got, want)
+	}
+	return toSumDBHashes(proof)
+}
+
+// FromSumDBTree converts the consistency proof from the format used by Go
+// checksum database.
+func FromSumDBTree(p tlog.TreeProof) [][]byte {
+	return fromSumDBHashes(p)
+}
+
+func toSumDBHashes(proof [][]byte) ([]tlog.Hash, error) {
+	res := make([]tlog.Hash, len(proof))
+	for i, h := range proof {
+		if got, want := len(h), tlog.HashSize; got != want {
+			return nil, fmt.Errorf("proof[%d] has unexpected size %d, want %d", i, got, want)
+		}
+		copy(res[i][:], h)
+	}
+	return res, nil
+}
+
+func fromSumDBHashes(p []tlog.Hash) [][]byte {
+	res := make([][]byte, len(p))
+	for i := range p {
+		res[i] = append([]byte(nil), p[i][:]...)
+	}
+	return res
+}
@@ -0,0 +1,135 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proof
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/transparency-dev/merkle/compact"
+	"golang.org/x/mod/sumdb/tlog"
+)
+
+// sumDBTree is an in-memory tree of the Go checksum database.
+type sumDBTree []tlog.Hash
+
+func (t sumDBTree) ReadHashes(indexes []int64) ([]tlog.Hash, error) {
+	res := make([]tlog.Hash, len(indexes))
+	for i, idx := range indexes {
+		res[i] = t[idx]
+	}
+	return res, nil
+}
+
+func TestSumDB(t *testing.T) {
+	const size = uint64(40)
+	nodes, roots := buildTree(t, size)
+	var tree sumDBTree
+	for i := uint64(0); i < size; i++ {
+		hashes, err := tlog.StoredHashes(int64(i), []byte(fmt.Sprintf("leaf %d", i)), tree)
+		if err != nil {
+			t.Fatalf("StoredHashes: %v", err)
+		}
+		tree = append(tree, hashes...)
+	}
+	rehash := func(n Nodes, err error) [][]byte {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("failed to build proof: %v", err)
+		}
+		proof, err := n.Rehash(getHashes(nodes, n.IDs), hasher.HashChildren)
+		if err != nil {
+			t.Fatalf("Rehash: %v", err)
+		}
+		return proof
+	}
+	toHash := func(b []byte) tlog.Hash {
+		var h tlog.Hash
+		copy(h[:], b)
+		return h
+	}
+
+	for size2 := uint64(1); size2 <= size; size2++ {
+		root := toHash(roots[size2])
+		for index := uint64(0); index < size2; index++ {
+			proof := rehash(Inclusion(index, size2))
+			got, err := ToSumDB(index, size2, proof)
+			if err != nil {
+				t.Fatalf("ToSumDB: %v", err)
+			}
+			want, err := tlog.ProveRecord(int64(size2), int64(index), tree)
+			if err != nil {
+				t.Fatalf("ProveRecord: %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("ToSumDB(%d, %d): diff (-want +got)\n%s", index, size2, diff)
+			}
+			leafHash := toHash(nodes[compact.NewNodeID(0, index)])
+			if err := tlog.CheckRecord(got, int64(size2), root, int64(index), leafHash); err != nil {
+				t.Errorf("ToSumDB(%d, %d): CheckRecord: %v", index, size2, err)
+			}
+			if diff := cmp.Diff(proof, FromSumDB(got)); diff != "" {
+				t.Errorf("FromSumDB(%d, %d): diff (-want +got)\n%s", index, size2, diff)
+			}
+		}
+		for size1 := uint64(1); size1 <= size2; size1++ {
+			proof := rehash(Consistency(size1, size2))
+			got, err := ToSumDBTree(size1, size2, proof)
+			if err != nil {
+				t.Fatalf("ToSumDBTree: %v", err)
+			}
+			want, err := tlog.ProveTree(int64(size2), int64(size1), tree)
+			if err != nil {
+				t.Fatalf("ProveTree: %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("ToSumDBTree(%d, %d): diff (-want +got)\n%s", size1, size2, diff)
+			}
+			if err := tlog.CheckTree(got, int64(size2), root, int64(size1), toHash(roots[size1])); err != nil {
+				t.Errorf("ToSumDBTree(%d, %d): CheckTree: %v", size1, size2, err)
+			}
+			if diff := cmp.Diff(proof, FromSumDBTree(got), cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("FromSumDBTree(%d, %d): diff (-want +got)\n%s", size1, size2, diff)
+			}
+		}
+	}
+}
+
+func TestToSumDBErrors(t *testing.T) {
+	hash := make([]byte, 32)
+	if _, err := ToSumDB(0, 2, nil); err == nil {
+		t.Error("ToSumDB accepted a short proof")
+	}
+	if _, err := ToSumDB(2, 2, [][]byte{hash}); err == nil {
+		t.Error("ToSumDB accepted an out of bounds index")
+	}
+	if _, err := ToSumDB(0, 2, [][]byte{hash[1:]}); err == nil {
+		t.Error("ToSumDB accepted a short hash")
+	}
+	if _, err := ToSumDB(0, 1<<63, nil); err == nil {
+		t.Error("ToSumDB accepted a too big tree")
+	}
+	if _, err := ToSumDBTree(0, 2, nil); err == nil {
+		t.Error("ToSumDBTree accepted size1 = 0")
+	}
+	if _, err := ToSumDBTree(3, 2, nil); err == nil {
+		t.Error("ToSumDBTree accepted size1 > size2")
+	}
+	if _, err := ToSumDBTree(1, 2, [][]byte{hash, hash}); err == nil {
+		t.Error("ToSumDBTree accepted a long proof")
+	}
+}
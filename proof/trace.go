@@ -0,0 +1,40 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proof
+
+import "github.com/transparency-dev/merkle"
+
+// HashOp describes a single HashChildren call.
+type HashOp struct {
+	Left, Right []byte // The hashes of the child nodes.
+	Out         []byte // The resulting hash.
+}
+
+// TracingHasher is a LogHasher which records all the HashChildren calls. This
+// is a debugging aid: verifying a proof with this hasher produces the full
+// trace of the computation, which can be compared against an expected one.
+type TracingHasher struct {
+	merkle.LogHasher
+	// Ops contains the recorded HashChildren calls, in order.
+	Ops []HashOp
+}
+
+// HashChildren computes the hash of the given children using the wrapped
+// hasher, and records the call.
+func (h *TracingHasher) HashChildren(l, r []byte) []byte {
+	out := h.LogHasher.HashChildren(l, r)
+	h.Ops = append(h.Ops, HashOp{Left: l, Right: r, Out: out})
+	return out
+}
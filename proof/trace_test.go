@@ -0,0 +1,50 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package proof
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestTracingHasher(t *testing.T) {
+	for i, p := range inclusionProofs[1:] {
+		t.Run(fmt.Sprintf("proof:%d", i), func(t *testing.T) {
+			index := p.leaf - 1
+			root := roots[p.size-1]
+			th := &TracingHasher{LogHasher: hasher}
+			if err := VerifyInclusion(th, index, p.size, hasher.HashLeaf(leaves[index]), p.proof, root); err != nil {
+				t.Fatalf("VerifyInclusion: %v", err)
+			}
+			if got, want := len(th.Ops), InclusionHashOps(index, p.size); got != want {
+				t.Fatalf("got %d ops, want %d", got, want)
+			}
+			for i, op := range th.Ops {
+				if got, want := op.Out, hasher.HashChildren(op.Left, op.Right); !bytes.Equal(got, want) {
+					t.Errorf("op %d: got %x, want %x", i, got, want)
+				}
+				if i > 0 {
+					if prev := th.Ops[i-1].Out; !bytes.Equal(op.Left, prev) && !bytes.Equal(op.Right, prev) {
+						t.Errorf("op %d does not use the result of op %d", i, i-1)
+					}
+				}
+			}
+			if n := len(th.Ops); n > 0 && !bytes.Equal(th.Ops[n-1].Out, root) {
+				t.Errorf("last op: got %x, want root %x", th.Ops[n-1].Out, root)
+			}
+		})
+	}
+}
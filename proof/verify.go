@@ -16,26 +16,53 @@ package proof
 
 import (
 	"bytes"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/bits"
 
 	"github.com/transparency-dev/merkle"
+	"github.com/transparency-dev/merkle/compact"
 )
 
+// ErrEmptyProofExpected is returned by the verifiers when the proof must be
+// empty, but it is not. For example, this is the case for a consistency proof
+// between equal tree sizes, or an inclusion proof in a tree of size 1.
+var ErrEmptyProofExpected = errors.New("proof must be empty")
+
 // RootMismatchError occurs when an inclusion proof fails.
 type RootMismatchError struct {
 	ExpectedRoot   []byte
 	CalculatedRoot []byte
+	// Size is the size of the tree whose root hash did not match.
+	Size uint64
+	// LeafIndex is the index of the leaf whose inclusion proof failed, or nil
+	// if the failure is not about a single leaf, e.g. for consistency proofs.
+	LeafIndex *uint64
 }
 
 func (e RootMismatchError) Error() string {
-	return fmt.Sprintf("calculated root:\n%v\n does not match expected root:\n%v", e.CalculatedRoot, e.ExpectedRoot)
+	msg := fmt.Sprintf("calculated root:\n%v\n does not match expected root:\n%v\n for tree size %d", e.CalculatedRoot, e.ExpectedRoot, e.Size)
+	if e.LeafIndex != nil {
+		msg += fmt.Sprintf(", leaf index %d", *e.LeafIndex)
+	}
+	return msg
+}
+
+// verifyMatch returns RootMismatchError if the calculated root hash of the tree
+// of the given size does not match the expected one.
+func verifyMatch(calculated, expected []byte, size uint64) error {
+	if !bytes.Equal(calculated, expected) {
+		return RootMismatchError{ExpectedRoot: expected, CalculatedRoot: calculated, Size: size}
+	}
+	return nil
 }
 
-func verifyMatch(calculated, expected []byte) error {
+// verifyLeafMatch is like verifyMatch, but also records the index of the leaf
+// whose inclusion is verified in the error.
+func verifyLeafMatch(calculated, expected []byte, size, index uint64) error {
 	if !bytes.Equal(calculated, expected) {
-		return RootMismatchError{ExpectedRoot: expected, CalculatedRoot: calculated}
+		return RootMismatchError{ExpectedRoot: expected, CalculatedRoot: calculated, Size: size, LeafIndex: &index}
 	}
 	return nil
 }
@@ -43,12 +70,139 @@ func verifyMatch(calculated, expected []byte) error {
 // VerifyInclusion verifies the correctness of the inclusion proof for the leaf
 // with the specified hash and index, relatively to the tree of the given size
 // and root hash. Requires 0 <= index < size.
+//
+// Only proof[:len(proof)] is read, and neither the proof slice nor the hashes
+// are modified, so it is safe to pass slices of a larger shared buffer.
 func VerifyInclusion(hasher merkle.LogHasher, index, size uint64, leafHash []byte, proof [][]byte, root []byte) error {
 	calcRoot, err := RootFromInclusionProof(hasher, index, size, leafHash, proof)
 	if err != nil {
 		return err
 	}
-	return verifyMatch(calcRoot, root)
+	return verifyLeafMatch(calcRoot, root, size, index)
+}
+
+// VerifyInclusionFromNodes verifies the inclusion proof like VerifyInclusion,
+// but takes the proof as an unordered map from node IDs to hashes, as some
+// servers return it. The nodes listed by Inclusion(index, size) are looked up
+// in the map, and the ephemeral node is rehashed. Other nodes are ignored.
+func VerifyInclusionFromNodes(hasher merkle.LogHasher, index, size uint64, leafHash []byte, nodes compact.NodeMap, root []byte) error {
+	proof, err := BuildInclusion(index, size, func(id compact.NodeID) ([]byte, bool) {
+		hash, ok := nodes[id]
+		return hash, ok
+	}, hasher.HashChildren)
+	if err != nil {
+		return err
+	}
+	return VerifyInclusion(hasher, index, size, leafHash, proof, root)
+}
+
+// UpgradeInclusion verifies that the leaf with the given index and hash is
+// included in the tree of size2 with root hash root2, given the leaf's
+// inclusion proof in the earlier tree of size1, and the consistency proof
+// between size1 and size2. This allows reusing an old inclusion proof rather
+// than fetching a new one. Requires 0 <= index < size1 <= size2.
+func UpgradeInclusion(hasher merkle.LogHasher, index, size1, size2 uint64, leafHash []byte, inclProof, consProof [][]byte, root2 []byte) error {
+	root1, err := RootFromInclusionProof(hasher, index, size1, leafHash, inclProof)
+	if err != nil {
+		return err
+	}
+	return VerifyConsistency(hasher, size1, size2, consProof, root1, root2)
+}
+
+// VerifyInclusionBytes verifies the inclusion proof like VerifyInclusion, but
+// accepts the proof as a concatenation of its hashes, as many wire formats
+// deliver it. The length of proofBytes must be a multiple of hasher.Size().
+func VerifyInclusionBytes(hasher merkle.LogHasher, index, size uint64, leafHash, proofBytes, root []byte) error {
+	hashSize := hasher.Size()
+	if len(proofBytes)%hashSize != 0 {
+		return fmt.Errorf("proof length %d is not a multiple of hash size %d", len(proofBytes), hashSize)
+	}
+	proof := make([][]byte, len(proofBytes)/hashSize)
+	for i := range proof {
+		proof[i] = proofBytes[i*hashSize : (i+1)*hashSize : (i+1)*hashSize]
+	}
+	return VerifyInclusion(hasher, index, size, leafHash, proof, root)
+}
+
+// VerifyInclusionHex verifies the inclusion proof like VerifyInclusion, but
+// accepts the leaf hash, proof hashes and root hash as hex strings. Returns an
+// error if any of the strings is not a valid hex encoding of a hash.
+func VerifyInclusionHex(hasher merkle.LogHasher, index, size uint64, leafHex string, proofHex []string, rootHex string) error {
+	decode := func(name, h string) ([]byte, error) {
+		b, err := hex.DecodeString(h)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		} else if got, want := len(b), hasher.Size(); got != want {
+			return nil, fmt.Errorf("%s: got %d bytes, want %d", name, got, want)
+		}
+		return b, nil
+	}
+	leafHash, err := decode("leaf hash", leafHex)
+	if err != nil {
+		return err
+	}
+	root, err := decode("root hash", rootHex)
+	if err != nil {
+		return err
+	}
+	proof := make([][]byte, len(proofHex))
+	for i, h := range proofHex {
+		if proof[i], err = decode(fmt.Sprintf("proof[%d]", i), h); err != nil {
+			return err
+		}
+	}
+	return VerifyInclusion(hasher, index, size, leafHash, proof, root)
+}
+
+// VerifyInclusionWithEphem verifies the inclusion proof like VerifyInclusion,
+// and additionally returns the ID and hash of the ephemeral node used in the
+// proof (see Nodes.Ephem). This allows caching the composite hash of the
+// ephemeral node. If the proof has no ephemeral node, the returned hash is nil.
+func VerifyInclusionWithEphem(hasher merkle.LogHasher, index, size uint64, leafHash []byte, proof [][]byte, root []byte) (compact.NodeID, []byte, error) {
+	if err := VerifyInclusion(hasher, index, size, leafHash, proof, root); err != nil {
+		return compact.NodeID{}, nil, err
+	}
+	// Note: Inclusion can't fail here, as VerifyInclusion checked the arguments.
+	n, err := Inclusion(index, size)
+	if err != nil {
+		return compact.NodeID{}, nil, err
+	}
+	ephem, begin, end := n.Ephem()
+	if begin == end {
+		return ephem, nil, nil
+	}
+	// The rehashed proof contains the ephemeral node hash at the position where
+	// the nodes comprising it begin.
+	return ephem, proof[begin], nil
+}
+
+// VerifyInclusionWithEphemHash verifies the inclusion proof like
+// VerifyInclusion, but takes the hash of the ephemeral node (see Nodes.Ephem)
+// separately from the rest of the proof. This allows using a precomputed
+// ephemeral node hash, e.g. a materialized one, rather than the nodes it is
+// computed from. The ephemHash must be nil iff the proof has no ephemeral
+// node, as returned by VerifyInclusionWithEphem.
+func VerifyInclusionWithEphemHash(hasher merkle.LogHasher, index, size uint64, leafHash []byte, pathProof [][]byte, ephemHash, root []byte) error {
+	n, err := Inclusion(index, size)
+	if err != nil {
+		return err
+	}
+	_, begin, end := n.Ephem()
+	if begin == end {
+		if ephemHash != nil {
+			return errors.New("proof has no ephemeral node, but its hash is provided")
+		}
+		return VerifyInclusion(hasher, index, size, leafHash, pathProof, root)
+	}
+	if ephemHash == nil {
+		return errors.New("ephemeral node hash is missing")
+	}
+	if got, want := len(pathProof), inclusionProofSize(index, size)-1; got != want {
+		return fmt.Errorf("wrong proof size %d, want %d", got, want)
+	}
+	proof := make([][]byte, 0, len(pathProof)+1)
+	proof = append(append(append(proof, pathProof[:begin]...), ephemHash), pathProof[begin:]...)
+	return VerifyInclusion(hasher, index, size, leafHash, proof, root)
 }
 
 // RootFromInclusionProof calculates the expected root hash for a tree of the
@@ -63,7 +217,9 @@ func RootFromInclusionProof(hasher merkle.LogHasher, index, size uint64, leafHas
 	}
 
 	inner, border := decompInclProof(index, size)
-	if got, want := len(proof), inner+border; got != want {
+	if got, want := len(proof), inner+border; want == 0 && got != 0 {
+		return nil, fmt.Errorf("size=%d, but got %d hashes: %w", size, got, ErrEmptyProofExpected)
+	} else if got != want {
 		return nil, fmt.Errorf("wrong proof size %d, want %d", got, want)
 	}
 
@@ -72,15 +228,123 @@ func RootFromInclusionProof(hasher merkle.LogHasher, index, size uint64, leafHas
 	return res, nil
 }
 
+// VerifyRangeInclusion verifies that the given leaf hashes are exactly the
+// contents of the [begin, end) range of leaves in the tree of the given size
+// and root hash. Requires 0 <= begin < end <= size.
+//
+// The proof consists of the hashes of the [0, begin) compact range, followed
+// by the hashes of the [end, size) compact range, as listed by the
+// compact.RangeNodes function. This is more efficient than verifying an
+// inclusion proof for each leaf.
+func VerifyRangeInclusion(hasher merkle.LogHasher, begin, end, size uint64, leafHashes [][]byte, proof [][]byte, root []byte) error {
+	if begin >= end || end > size {
+		return fmt.Errorf("invalid range [%d, %d) for tree size %d", begin, end, size)
+	}
+	if got, want := uint64(len(leafHashes)), end-begin; got != want {
+		return fmt.Errorf("got %d leaf hashes, want %d", got, want)
+	}
+	left := compact.RangeSize(0, begin)
+	if got, want := len(proof), left+compact.RangeSize(end, size); got != want {
+		return fmt.Errorf("wrong proof size %d, want %d", got, want)
+	}
+	hashSize := hasher.Size()
+	for _, hashes := range [][][]byte{leafHashes, proof} {
+		for _, hash := range hashes {
+			if got := len(hash); got != hashSize {
+				return fmt.Errorf("hash has unexpected size %d, want %d", got, hashSize)
+			}
+		}
+	}
+
+	rf := &compact.RangeFactory{Hash: hasher.HashChildren}
+	// Note: The range takes ownership of its hashes slice, and appending leaves
+	// overwrites its trailing entries when they merge. Copy the prefix, so that
+	// the caller's proof is not modified.
+	cr, err := rf.NewRange(0, begin, append([][]byte(nil), proof[:left]...))
+	if err != nil {
+		return err
+	}
+	for _, hash := range leafHashes {
+		if err := cr.Append(hash, nil); err != nil {
+			return err
+		}
+	}
+	right, err := rf.NewRange(end, size, proof[left:])
+	if err != nil {
+		return err
+	}
+	if err := cr.AppendRange(right, nil); err != nil {
+		return err
+	}
+	calcRoot, err := cr.GetRootHash(nil)
+	if err != nil {
+		return err
+	}
+	return verifyMatch(calcRoot, root, size)
+}
+
 // VerifyConsistency checks that the passed-in consistency proof is valid
 // between the passed in tree sizes, with respect to the corresponding root
 // hashes. Requires 0 < size1 <= size2.
+//
+// The edge cases are handled as follows:
+//   - size1 > size2 is always an error.
+//   - size1 == size2 (including 0) requires an empty proof and root1 == root2.
+//   - size1 == 0 < size2 is an error, because a consistency proof from an
+//     empty tree is meaningless. Callers should handle this case separately.
+//   - size1 < size2 requires a non-empty proof.
 func VerifyConsistency(hasher merkle.LogHasher, size1, size2 uint64, proof [][]byte, root1, root2 []byte) error {
 	hash2, err := RootFromConsistencyProof(hasher, size1, size2, proof, root1)
 	if err != nil {
 		return err
 	}
-	return verifyMatch(hash2, root2)
+	return verifyMatch(hash2, root2, size2)
+}
+
+// VerifyConsistencyFromRange checks the consistency proof like
+// VerifyConsistency, but derives root1 from the compact range r1 of the
+// smaller tree, which must begin at index 0. The size of this tree is
+// r1.End(). This is useful for monitors that store the compact range of the
+// last seen tree rather than its root hash.
+func VerifyConsistencyFromRange(hasher merkle.LogHasher, r1 *compact.Range, size2 uint64, proof [][]byte, root2 []byte) error {
+	if r1.Begin() != 0 {
+		return fmt.Errorf("range begins at %d, want 0", r1.Begin())
+	}
+	root1 := hasher.EmptyRoot()
+	if r1.End() != 0 {
+		var err error
+		if root1, err = r1.GetRootHash(nil); err != nil {
+			return err
+		}
+	}
+	return VerifyConsistency(hasher, r1.End(), size2, proof, root1, root2)
+}
+
+// InclusionItem is an inclusion proof for the leaf with the given index and
+// hash, as accepted by VerifyInclusionsFromRange.
+type InclusionItem struct {
+	Index    uint64
+	LeafHash []byte
+	Proof    [][]byte
+}
+
+// VerifyInclusionsFromRange verifies all the given inclusion proofs against
+// the tree represented by the compact range r, which must begin at index 0.
+// The size of this tree is r.End(). The root hash is computed once for all the
+// proofs, so this is convenient for checking a batch of generated proofs
+// before serving them. Returns the error for the first item that does not
+// verify, annotated with its position in items.
+func VerifyInclusionsFromRange(hasher merkle.LogHasher, r *compact.Range, items []InclusionItem) error {
+	root, err := r.GetRootHash(nil)
+	if err != nil {
+		return err
+	}
+	for i, item := range items {
+		if err := VerifyInclusion(hasher, item.Index, r.End(), item.LeafHash, item.Proof, root); err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+	return nil
 }
 
 // RootFromConsistencyProof calculates the expected root hash for a tree of the
@@ -93,7 +357,7 @@ func RootFromConsistencyProof(hasher merkle.LogHasher, size1, size2 uint64, proo
 		return nil, fmt.Errorf("size2 (%d) < size1 (%d)", size1, size2)
 	case size1 == size2:
 		if len(proof) > 0 {
-			return nil, errors.New("size1=size2, but proof is not empty")
+			return nil, fmt.Errorf("size1=size2, but got %d hashes: %w", len(proof), ErrEmptyProofExpected)
 		}
 		return root1, nil
 	case size1 == 0:
@@ -102,7 +366,12 @@ func RootFromConsistencyProof(hasher merkle.LogHasher, size1, size2 uint64, proo
 		return nil, errors.New("empty proof")
 	}
 
-	inner, border := decompInclProof(size1-1, size2)
+	// Check the proof size upfront, so that a proof for a different size2 is
+	// reported as such, rather than as a root mismatch.
+	if got, want := len(proof), ConsistencyProofSize(size1, size2); got != want {
+		return nil, fmt.Errorf("wrong proof size %d, want %d: the proof is not for sizes %d and %d", got, want, size1, size2)
+	}
+	inner, _ := decompInclProof(size1-1, size2)
 	shift := bits.TrailingZeros64(size1)
 	inner -= shift // Note: shift < inner if size1 < size2.
 
@@ -111,9 +380,6 @@ func RootFromConsistencyProof(hasher merkle.LogHasher, size1, size2 uint64, proo
 	if size1 == 1<<uint(shift) { // Unless size1 is that very 2^shift.
 		seed, start = root1, 0
 	}
-	if got, want := len(proof), start+inner+border; got != want {
-		return nil, fmt.Errorf("wrong proof size %d, want %d", got, want)
-	}
 	proof = proof[start:]
 	// Now len(proof) == inner+border, and proof is effectively a suffix of
 	// inclusion proof for entry |size1-1| in a tree of size |size2|.
@@ -122,7 +388,7 @@ func RootFromConsistencyProof(hasher merkle.LogHasher, size1, size2 uint64, proo
 	mask := (size1 - 1) >> uint(shift) // Start chaining from level |shift|.
 	hash1 := chainInnerRight(hasher, seed, proof[:inner], mask)
 	hash1 = chainBorderRight(hasher, hash1, proof[inner:])
-	if err := verifyMatch(hash1, root1); err != nil {
+	if err := verifyMatch(hash1, root1, size1); err != nil {
 		return nil, err
 	}
 
@@ -132,6 +398,68 @@ func RootFromConsistencyProof(hasher merkle.LogHasher, size1, size2 uint64, proo
 	return hash2, nil
 }
 
+// ConsistencyProofSize returns the length of a valid consistency proof
+// between the given tree sizes. Returns -1 if no proof can be valid, i.e. if
+// size1 > size2, or 0 = size1 < size2.
+func ConsistencyProofSize(size1, size2 uint64) int {
+	switch {
+	case size1 > size2 || size1 == 0 && size2 != 0:
+		return -1
+	case size1 == size2:
+		return 0
+	}
+	inner, border := decompInclProof(size1-1, size2)
+	shift := bits.TrailingZeros64(size1)
+	size := inner - shift + border
+	if size1 != 1<<uint(shift) { // The proof includes the seed.
+		size++
+	}
+	return size
+}
+
+// NeedsConsistencyProof returns whether a consistency proof between the given
+// tree sizes contains any hashes, i.e. whether it is worth fetching. Returns
+// false if size1 == 0, in which case consistency is trivial, and if size1 >=
+// size2, in which case the proof is empty or can not exist.
+func NeedsConsistencyProof(size1, size2 uint64) bool {
+	return size1 != 0 && size1 < size2
+}
+
+// InclusionHashOps returns the number of HashChildren calls that
+// VerifyInclusion performs for a leaf index in a tree of the given size. This
+// can be used for predicting the cost of verification. Returns 0 if index >=
+// size, in which case the proof is rejected without hashing.
+func InclusionHashOps(index, size uint64) int {
+	return max(inclusionProofSize(index, size), 0)
+}
+
+// ConsistencyHashOps returns the number of HashChildren calls that
+// VerifyConsistency performs for a well-formed proof between the given tree
+// sizes. Returns 0 for sizes that don't require a proof, or are invalid.
+func ConsistencyHashOps(size1, size2 uint64) int {
+	if size1 == 0 || size1 >= size2 {
+		return 0
+	}
+	inner, border := decompInclProof(size1-1, size2)
+	shift := bits.TrailingZeros64(size1)
+	inner -= shift
+	// The first root is computed only from the hashes to the left of the path,
+	// which correspond to one bits in the mask. The second root uses them all.
+	mask := (size1 - 1) >> uint(shift)
+	left := bits.OnesCount64(mask & (1<<uint(inner) - 1))
+	return left + inner + 2*border
+}
+
+// inclusionProofSize returns the length of a valid inclusion proof for the
+// given leaf index in a tree of the given size. Returns -1 if index >= size.
+func inclusionProofSize(index, size uint64) int {
+	if index >= size {
+		return -1
+	}
+	inner, border := decompInclProof(index, size)
+	return inner + border
+}
+
 // decompInclProof breaks down inclusion proof for a leaf at the specified
 // |index| in a tree of the specified |size| into 2 components. The splitting
 // point between them is where paths to leaves |index| and |size-1| diverge.
@@ -17,11 +17,15 @@ package proof
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/rand/v2"
 	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/transparency-dev/merkle"
+	"github.com/transparency-dev/merkle/compact"
 	"github.com/transparency-dev/merkle/rfc6962"
 )
 
@@ -296,6 +300,83 @@ func TestVerifyInclusionSingleEntry(t *testing.T) {
 	}
 }
 
+func TestVerifyInclusionDoesNotReadBeyondLen(t *testing.T) {
+	const size = uint64(13)
+	nodes, roots := buildTree(t, size)
+	sentinel := bytes.Repeat([]byte{0xAA}, 32)
+	for index := uint64(0); index < size; index++ {
+		n, err := Inclusion(index, size)
+		if err != nil {
+			t.Fatalf("Inclusion: %v", err)
+		}
+		proof, err := n.Rehash(getHashes(nodes, n.IDs), hasher.HashChildren)
+		if err != nil {
+			t.Fatalf("Rehash: %v", err)
+		}
+		// Back the proof and each of its hashes with larger arrays, and fill the
+		// spare capacity with sentinels.
+		backing := make([][]byte, len(proof)+3)
+		for i := range backing {
+			backing[i] = sentinel
+		}
+		for i, h := range proof {
+			buf := append(append(make([]byte, 0, 2*len(h)), h...), sentinel...)
+			backing[i] = buf[:len(h)]
+		}
+		leaf := append(append([]byte(nil), nodes[compact.NewNodeID(0, index)]...), sentinel...)
+		leaf = leaf[:len(leaf)-len(sentinel)]
+
+		if err := VerifyInclusion(hasher, index, size, leaf, backing[:len(proof)], roots[size]); err != nil {
+			t.Errorf("VerifyInclusion(%d): %v", index, err)
+		}
+		for i := len(proof); i < len(backing); i++ {
+			if !bytes.Equal(backing[i], sentinel) {
+				t.Errorf("VerifyInclusion(%d): modified proof entry %d beyond len", index, i)
+			}
+		}
+		for i, h := range backing[:len(proof)] {
+			if !bytes.Equal(h, proof[i]) || !bytes.Equal(h[len(h):cap(h)], sentinel) {
+				t.Errorf("VerifyInclusion(%d): modified proof hash %d", index, i)
+			}
+		}
+		if !bytes.Equal(leaf[len(leaf):cap(leaf)], sentinel) {
+			t.Errorf("VerifyInclusion(%d): modified bytes beyond the leaf hash", index)
+		}
+	}
+}
+
+func TestVerifyInclusionFromNodes(t *testing.T) {
+	const size = uint64(23)
+	nodes, roots := buildTree(t, size)
+	rnd := rand.New(rand.NewPCG(3, 4))
+	for size2 := uint64(1); size2 <= size; size2++ {
+		for index := uint64(0); index < size2; index++ {
+			n, err := Inclusion(index, size2)
+			if err != nil {
+				t.Fatalf("Inclusion: %v", err)
+			}
+			// Build the map by inserting the nodes in a random order, together
+			// with some unrelated nodes.
+			m := make(compact.NodeMap)
+			for _, i := range rnd.Perm(len(n.IDs)) {
+				m[n.IDs[i]] = nodes[n.IDs[i]]
+			}
+			m[compact.NewNodeID(0, index)] = sha256SomeHash
+			leaf := nodes[compact.NewNodeID(0, index)]
+			if err := VerifyInclusionFromNodes(hasher, index, size2, leaf, m, roots[size2]); err != nil {
+				t.Errorf("VerifyInclusionFromNodes(%d, %d): %v", index, size2, err)
+			}
+			if len(n.IDs) == 0 {
+				continue
+			}
+			delete(m, n.IDs[rnd.IntN(len(n.IDs))])
+			if err := VerifyInclusionFromNodes(hasher, index, size2, leaf, m, roots[size2]); err == nil {
+				t.Errorf("VerifyInclusionFromNodes(%d, %d): succeeded with a missing node", index, size2)
+			}
+		}
+	}
+}
+
 func TestVerifyInclusion(t *testing.T) {
 	proof := [][]byte{}
 
@@ -328,6 +409,176 @@ func TestVerifyInclusion(t *testing.T) {
 	}
 }
 
+func TestVerifyConsistencyStaleSize(t *testing.T) {
+	nodes, roots := buildTree(t, 9)
+	n, err := Consistency(5, 7)
+	if err != nil {
+		t.Fatalf("Consistency: %v", err)
+	}
+	proof, err := n.Rehash(getHashes(nodes, n.IDs), hasher.HashChildren)
+	if err != nil {
+		t.Fatalf("Rehash: %v", err)
+	}
+	// The proof is for size2 = 7, but verified against the tree of size 9.
+	err = VerifyConsistency(hasher, 5, 9, proof, roots[5], roots[9])
+	if err == nil {
+		t.Fatal("VerifyConsistency succeeded with a proof for a different size")
+	}
+	var mismatch RootMismatchError
+	if errors.As(err, &mismatch) {
+		t.Errorf("VerifyConsistency: got %v, want proof size error", err)
+	} else if want := "not for sizes 5 and 9"; !strings.Contains(err.Error(), want) {
+		t.Errorf("VerifyConsistency: got %q, want it to contain %q", err, want)
+	}
+}
+
+func TestVerifyInclusionsFromRange(t *testing.T) {
+	const size = uint64(21)
+	nodes, _ := buildTree(t, size)
+	rf := &compact.RangeFactory{Hash: hasher.HashChildren}
+	r, err := rf.NewRangeFromNodes(0, size, nodes)
+	if err != nil {
+		t.Fatalf("NewRangeFromNodes: %v", err)
+	}
+	item := func(index uint64) InclusionItem {
+		p, err := BuildInclusion(index, size, func(id compact.NodeID) ([]byte, bool) {
+			hash, ok := nodes[id]
+			return hash, ok
+		}, hasher.HashChildren)
+		if err != nil {
+			t.Fatalf("BuildInclusion: %v", err)
+		}
+		return InclusionItem{Index: index, LeafHash: nodes[compact.NewNodeID(0, index)], Proof: p}
+	}
+
+	good := []InclusionItem{item(0), item(7), item(16), item(20)}
+	if err := VerifyInclusionsFromRange(hasher, r, good); err != nil {
+		t.Errorf("VerifyInclusionsFromRange: %v", err)
+	}
+	if err := VerifyInclusionsFromRange(hasher, r, nil); err != nil {
+		t.Errorf("VerifyInclusionsFromRange(nil): %v", err)
+	}
+
+	wrongLeaf := item(9)
+	wrongLeaf.LeafHash = sha256SomeHash
+	wrongIndex := item(3)
+	wrongIndex.Index = 4
+	for _, tc := range []struct {
+		desc  string
+		items []InclusionItem
+		want  string
+	}{
+		{desc: "wrong-leaf", items: []InclusionItem{good[0], good[1], wrongLeaf, good[2]}, want: "item 2:"},
+		{desc: "wrong-index", items: []InclusionItem{wrongIndex, good[3]}, want: "item 0:"},
+		{desc: "short-proof", items: []InclusionItem{good[0], {Index: 5, LeafHash: sha256SomeHash}}, want: "item 1:"},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := VerifyInclusionsFromRange(hasher, r, tc.items)
+			if err == nil {
+				t.Fatal("VerifyInclusionsFromRange succeeded")
+			}
+			if !strings.HasPrefix(err.Error(), tc.want) {
+				t.Errorf("VerifyInclusionsFromRange: got %q, want prefix %q", err, tc.want)
+			}
+		})
+	}
+
+	if err := VerifyInclusionsFromRange(hasher, rf.NewEmptyRange(3), good); err == nil {
+		t.Error("VerifyInclusionsFromRange succeeded with a range not starting at 0")
+	}
+}
+
+// TestRootMismatchCalculatedRoot checks that the RootMismatchError returned
+// for a wrong root always carries the true root, including in edge cases like
+// single-leaf trees, perfect trees, and the last leaf of the tree.
+func TestRootMismatchCalculatedRoot(t *testing.T) {
+	const size = uint64(17)
+	nodes, roots := buildTree(t, size)
+	for size2 := uint64(1); size2 <= size; size2++ {
+		for index := uint64(0); index < size2; index++ {
+			n, err := Inclusion(index, size2)
+			if err != nil {
+				t.Fatalf("Inclusion: %v", err)
+			}
+			proof, err := n.Rehash(getHashes(nodes, n.IDs), hasher.HashChildren)
+			if err != nil {
+				t.Fatalf("Rehash: %v", err)
+			}
+			leaf := nodes[compact.NewNodeID(0, index)]
+			err = VerifyInclusion(hasher, index, size2, leaf, proof, sha256SomeHash)
+			var mismatch RootMismatchError
+			if !errors.As(err, &mismatch) {
+				t.Fatalf("VerifyInclusion(%d, %d): %v, want RootMismatchError", index, size2, err)
+			}
+			if got, want := mismatch.CalculatedRoot, roots[size2]; !bytes.Equal(got, want) {
+				t.Errorf("VerifyInclusion(%d, %d): CalculatedRoot %x, want %x", index, size2, got, want)
+			}
+			if got, want := mismatch.ExpectedRoot, sha256SomeHash; !bytes.Equal(got, want) {
+				t.Errorf("VerifyInclusion(%d, %d): ExpectedRoot %x, want %x", index, size2, got, want)
+			}
+			if got, want := mismatch.Size, size2; got != want {
+				t.Errorf("VerifyInclusion(%d, %d): Size %d, want %d", index, size2, got, want)
+			}
+			if got := mismatch.LeafIndex; got == nil || *got != index {
+				t.Errorf("VerifyInclusion(%d, %d): LeafIndex %v, want %d", index, size2, got, index)
+			} else if want := fmt.Sprintf("leaf index %d", index); !strings.Contains(err.Error(), want) {
+				t.Errorf("VerifyInclusion(%d, %d): error %q does not contain %q", index, size2, err, want)
+			}
+		}
+		for size1 := uint64(1); size1 <= size2; size1++ {
+			n, err := Consistency(size1, size2)
+			if err != nil {
+				t.Fatalf("Consistency: %v", err)
+			}
+			proof, err := n.Rehash(getHashes(nodes, n.IDs), hasher.HashChildren)
+			if err != nil {
+				t.Fatalf("Rehash: %v", err)
+			}
+			err = VerifyConsistency(hasher, size1, size2, proof, roots[size1], sha256SomeHash)
+			var mismatch RootMismatchError
+			if !errors.As(err, &mismatch) {
+				t.Fatalf("VerifyConsistency(%d, %d): %v, want RootMismatchError", size1, size2, err)
+			}
+			if got, want := mismatch.CalculatedRoot, roots[size2]; !bytes.Equal(got, want) {
+				t.Errorf("VerifyConsistency(%d, %d): CalculatedRoot %x, want %x", size1, size2, got, want)
+			}
+			if got, want := mismatch.Size, size2; got != want {
+				t.Errorf("VerifyConsistency(%d, %d): Size %d, want %d", size1, size2, got, want)
+			}
+			if got := mismatch.LeafIndex; got != nil {
+				t.Errorf("VerifyConsistency(%d, %d): LeafIndex %d, want nil", size1, size2, *got)
+			}
+		}
+	}
+}
+
+// TestVerifyRangeInclusionDoesNotModifyProof checks that the proof is not
+// modified, in particular for odd begin values where the first appended leaf
+// merges with the nodes of the [0, begin) prefix.
+func TestVerifyRangeInclusionDoesNotModifyProof(t *testing.T) {
+	const size = uint64(16)
+	nodes, roots := buildTree(t, size)
+	for _, sz := range []uint64{8, 13, size} {
+		for begin := uint64(1); begin < sz; begin += 2 {
+			for end := begin + 1; end <= sz; end++ {
+				ids := compact.RangeNodes(end, sz, compact.RangeNodes(0, begin, nil))
+				proof := getHashes(nodes, ids)
+				want := append([][]byte(nil), proof...)
+				var leafHashes [][]byte
+				for i := begin; i < end; i++ {
+					leafHashes = append(leafHashes, nodes[compact.NewNodeID(0, i)])
+				}
+				if err := VerifyRangeInclusion(hasher, begin, end, sz, leafHashes, proof, roots[sz]); err != nil {
+					t.Fatalf("VerifyRangeInclusion(%d, %d, %d): %v", begin, end, sz, err)
+				}
+				if diff := cmp.Diff(want, proof); diff != "" {
+					t.Errorf("VerifyRangeInclusion(%d, %d, %d) modified the proof: diff (-want +got)\n%s", begin, end, sz, diff)
+				}
+			}
+		}
+	}
+}
+
 func TestVerifyConsistency(t *testing.T) {
 	root1 := []byte("don't care 1")
 	root2 := []byte("don't care 2")
@@ -335,32 +586,34 @@ func TestVerifyConsistency(t *testing.T) {
 	proof2 := [][]byte{sha256EmptyTreeHash}
 
 	tests := []struct {
+		desc         string
 		size1, size2 uint64
 		root1, root2 []byte
 		proof        [][]byte
 		wantErr      bool
 	}{
-		{0, 0, root1, root2, proof1, true},
-		{1, 1, root1, root2, proof1, true},
+		// Equal sizes require equal roots.
+		{"0:0:roots-differ", 0, 0, root1, root2, proof1, true},
+		{"1:1:roots-differ", 1, 1, root1, root2, proof1, true},
 		// Sizes that are always consistent.
-		{0, 0, root1, root1, proof1, false},
-		{0, 1, root1, root2, proof1, true},
-		{1, 1, root2, root2, proof1, false},
+		{"0:0:roots-match", 0, 0, root1, root1, proof1, false},
+		{"0:1:from-empty", 0, 1, root1, root2, proof1, true},
+		{"1:1:roots-match", 1, 1, root2, root2, proof1, false},
 		// Time travel to the past.
-		{1, 0, root1, root2, proof1, true},
-		{2, 1, root1, root2, proof1, true},
+		{"1:0:shrink", 1, 0, root1, root2, proof1, true},
+		{"2:1:shrink", 2, 1, root1, root2, proof1, true},
 		// Empty proof.
-		{1, 2, root1, root2, proof1, true},
+		{"1:2:empty-proof", 1, 2, root1, root2, proof1, true},
 		// Roots don't match.
-		{0, 0, sha256EmptyTreeHash, root2, proof1, true},
-		{1, 1, sha256EmptyTreeHash, root2, proof1, true},
+		{"0:0:wrong-root2", 0, 0, sha256EmptyTreeHash, root2, proof1, true},
+		{"1:1:wrong-root2", 1, 1, sha256EmptyTreeHash, root2, proof1, true},
 		// Roots match but the proof is not empty.
-		{0, 0, sha256EmptyTreeHash, sha256EmptyTreeHash, proof2, true},
-		{0, 1, sha256EmptyTreeHash, sha256EmptyTreeHash, proof2, true},
-		{1, 1, sha256EmptyTreeHash, sha256EmptyTreeHash, proof2, true},
+		{"0:0:non-empty-proof", 0, 0, sha256EmptyTreeHash, sha256EmptyTreeHash, proof2, true},
+		{"0:1:non-empty-proof", 0, 1, sha256EmptyTreeHash, sha256EmptyTreeHash, proof2, true},
+		{"1:1:non-empty-proof", 1, 1, sha256EmptyTreeHash, sha256EmptyTreeHash, proof2, true},
 	}
-	for i, p := range tests {
-		t.Run(fmt.Sprintf("test:%d:size:%d-%d", i, p.size1, p.size2), func(t *testing.T) {
+	for _, p := range tests {
+		t.Run(p.desc, func(t *testing.T) {
 			err := verifierConsistencyCheck(hasher, p.size1, p.size2, p.proof, p.root1, p.root2)
 			if p.wantErr && err == nil {
 				t.Errorf("Incorrectly verified")
@@ -381,6 +634,577 @@ func TestVerifyConsistency(t *testing.T) {
 	}
 }
 
+// TestVerifyExtremeSizes checks proofs for tree sizes and indices near 2^64,
+// including the corrupted inputs in which index or size computations wrap
+// around. All leaves of the tree are equal, so that the hash of a perfect
+// subtree depends only on its level, and can be computed cheaply.
+func TestVerifyExtremeSizes(t *testing.T) {
+	var levels [64][]byte
+	levels[0] = hasher.HashLeaf([]byte("leaf"))
+	for i := 1; i < len(levels); i++ {
+		levels[i] = hasher.HashChildren(levels[i-1], levels[i-1])
+	}
+	hashes := func(ids []compact.NodeID) [][]byte {
+		res := make([][]byte, len(ids))
+		for i, id := range ids {
+			res[i] = levels[id.Level]
+		}
+		return res
+	}
+	root := func(size uint64) []byte {
+		h := hashes(compact.RangeNodes(0, size, nil))
+		res := h[len(h)-1]
+		for i := len(h) - 2; i >= 0; i-- {
+			res = hasher.HashChildren(h[i], res)
+		}
+		return res
+	}
+
+	const maxSize = ^uint64(0)
+	sizes := []uint64{maxSize, maxSize - 1, 1<<63 + 1, 1 << 63, 1<<63 - 1, 3 << 62}
+	for _, size := range sizes {
+		for _, index := range []uint64{0, 1, 1 << 62, size/2 + 1, size - 2, size - 1} {
+			t.Run(fmt.Sprintf("inclusion:%d:%d", index, size), func(t *testing.T) {
+				n, err := Inclusion(index, size)
+				if err != nil {
+					t.Fatalf("Inclusion: %v", err)
+				}
+				proof, err := n.Rehash(hashes(n.IDs), hasher.HashChildren)
+				if err != nil {
+					t.Fatalf("Rehash: %v", err)
+				}
+				r := root(size)
+				if err := VerifyInclusion(hasher, index, size, levels[0], proof, r); err != nil {
+					t.Fatalf("VerifyInclusion: %v", err)
+				}
+				// Note: Since all leaves are equal, some corruptions, like a different
+				// index, can result in a valid proof. Only test the ones that can't.
+				probes := []inclusionProbe{
+					{maxSize, size, r, levels[0], proof, "index = max"},
+					{index, size, r, levels[0], proof[1:], "removed component"},
+					{index, size, r, levels[0], extend(proof, r), "trailing root"},
+					{index, size, levels[1], levels[0], proof, "wrong root"},
+				}
+				if size == maxSize {
+					probes = append(probes, inclusionProbe{index, size + 1, r, levels[0], proof, "size + 1"})
+				}
+				for _, p := range probes {
+					if err := VerifyInclusion(hasher, p.leafIndex, p.treeSize, p.leafHash, p.proof, p.root); err == nil {
+						t.Errorf("incorrectly verified against: %s", p.desc)
+					}
+				}
+			})
+		}
+		for _, size1 := range append([]uint64{1, 2, 3, 1 << 62}, sizes...) {
+			if size1 >= size {
+				continue
+			}
+			t.Run(fmt.Sprintf("consistency:%d:%d", size1, size), func(t *testing.T) {
+				n, err := Consistency(size1, size)
+				if err != nil {
+					t.Fatalf("Consistency: %v", err)
+				}
+				proof, err := n.Rehash(hashes(n.IDs), hasher.HashChildren)
+				if err != nil {
+					t.Fatalf("Rehash: %v", err)
+				}
+				r1, r2 := root(size1), root(size)
+				if err := VerifyConsistency(hasher, size1, size, proof, r1, r2); err != nil {
+					t.Fatalf("VerifyConsistency: %v", err)
+				}
+				probes := []consistencyProbe{
+					{maxSize, size, r1, r2, proof, "size1 = max"},
+					{size1, size, r1, r2, proof[1:], "removed component"},
+					{size1, size, r1, r2, extend(proof, r2), "trailing root2"},
+					{size1, size, r2, r1, proof, "swapped roots"},
+				}
+				if size == maxSize {
+					probes = append(probes, consistencyProbe{size1, size + 1, r1, r2, proof, "size2 + 1"})
+				}
+				for _, p := range probes {
+					if err := VerifyConsistency(hasher, p.size1, p.size2, p.proof, p.root1, p.root2); err == nil {
+						t.Errorf("incorrectly verified against: %s", p.desc)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestVerifyConsistencyFromRange(t *testing.T) {
+	const size = uint64(40)
+	nodes, roots := buildTree(t, size)
+	rf := &compact.RangeFactory{Hash: hasher.HashChildren}
+	for size2 := uint64(1); size2 <= size; size2++ {
+		for size1 := uint64(1); size1 <= size2; size1++ {
+			r1, err := rf.NewRangeFromNodes(0, size1, nodes)
+			if err != nil {
+				t.Fatalf("NewRange: %v", err)
+			}
+			n, err := Consistency(size1, size2)
+			if err != nil {
+				t.Fatalf("Consistency: %v", err)
+			}
+			proof, err := n.Rehash(getHashes(nodes, n.IDs), hasher.HashChildren)
+			if err != nil {
+				t.Fatalf("Rehash: %v", err)
+			}
+			if err := VerifyConsistencyFromRange(hasher, r1, size2, proof, roots[size2]); err != nil {
+				t.Errorf("VerifyConsistencyFromRange(%d, %d): %v", size1, size2, err)
+			}
+			if err := VerifyConsistencyFromRange(hasher, r1, size2, proof, sha256SomeHash); err == nil {
+				t.Errorf("VerifyConsistencyFromRange(%d, %d): accepted wrong root2", size1, size2)
+			}
+		}
+	}
+
+	if err := VerifyConsistencyFromRange(hasher, rf.NewEmptyRange(0), 0, nil, roots[0]); err != nil {
+		t.Errorf("VerifyConsistencyFromRange(0, 0): %v", err)
+	}
+	if err := VerifyConsistencyFromRange(hasher, rf.NewEmptyRange(5), 5, nil, roots[0]); err == nil {
+		t.Error("VerifyConsistencyFromRange accepted a range not starting at 0")
+	}
+	r1, err := rf.NewRangeFromNodes(4, 8, nodes)
+	if err != nil {
+		t.Fatalf("NewRange: %v", err)
+	}
+	if err := VerifyConsistencyFromRange(hasher, r1, 8, nil, roots[8]); err == nil {
+		t.Error("VerifyConsistencyFromRange accepted a non-empty range not starting at 0")
+	} else if want := "range begins at 4"; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("VerifyConsistencyFromRange: got error %q, want %q", err, want)
+	}
+}
+
+func TestUpgradeInclusion(t *testing.T) {
+	const size = uint64(24)
+	nodes, roots := buildTree(t, size)
+	rehash := func(n Nodes, err error) [][]byte {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("failed to build proof: %v", err)
+		}
+		proof, err := n.Rehash(getHashes(nodes, n.IDs), hasher.HashChildren)
+		if err != nil {
+			t.Fatalf("Rehash: %v", err)
+		}
+		return proof
+	}
+	for size2 := uint64(1); size2 <= size; size2++ {
+		for size1 := uint64(1); size1 <= size2; size1++ {
+			cons := rehash(Consistency(size1, size2))
+			for index := uint64(0); index < size1; index++ {
+				incl := rehash(Inclusion(index, size1))
+				leafHash := nodes[compact.NewNodeID(0, index)]
+				if err := UpgradeInclusion(hasher, index, size1, size2, leafHash, incl, cons, roots[size2]); err != nil {
+					t.Errorf("UpgradeInclusion(%d, %d, %d): %v", index, size1, size2, err)
+				}
+				if err := UpgradeInclusion(hasher, index, size1, size2, leafHash, incl, cons, roots[size1]); err == nil && size1 != size2 {
+					t.Errorf("UpgradeInclusion(%d, %d, %d): accepted root1 as root2", index, size1, size2)
+				}
+				wrongLeaf := nodes[compact.NewNodeID(0, (index+1)%size1)]
+				if err := UpgradeInclusion(hasher, index, size1, size2, wrongLeaf, incl, cons, roots[size2]); err == nil && size1 != 1 {
+					t.Errorf("UpgradeInclusion(%d, %d, %d): accepted wrong leaf", index, size1, size2)
+				}
+			}
+		}
+	}
+}
+
+func TestVerifyRangeInclusion(t *testing.T) {
+	const size = uint64(20)
+	nodes, roots := buildTree(t, size)
+	leafHashes := make([][]byte, size)
+	for i := range leafHashes {
+		leafHashes[i] = nodes[compact.NewNodeID(0, uint64(i))]
+	}
+	rangeProof := func(begin, end, sz uint64) [][]byte {
+		ids := compact.RangeNodes(0, begin, nil)
+		ids = compact.RangeNodes(end, sz, ids)
+		return getHashes(nodes, ids)
+	}
+
+	for sz := uint64(1); sz <= size; sz++ {
+		for begin := uint64(0); begin < sz; begin++ {
+			for end := begin + 1; end <= sz; end++ {
+				proof := rangeProof(begin, end, sz)
+				if err := VerifyRangeInclusion(hasher, begin, end, sz, leafHashes[begin:end], proof, roots[sz]); err != nil {
+					t.Errorf("VerifyRangeInclusion(%d, %d, %d): %v", begin, end, sz, err)
+				}
+			}
+		}
+	}
+
+	// The [2, 5) range in the tree of size 8 is proven by nodes (1, 0), (0, 5),
+	// and (1, 3).
+	begin, end, sz := uint64(2), uint64(5), uint64(8)
+	proof := rangeProof(begin, end, sz)
+	want := getHashes(nodes, []compact.NodeID{compact.NewNodeID(1, 0), compact.NewNodeID(0, 5), compact.NewNodeID(1, 3)})
+	if diff := cmp.Diff(want, proof); diff != "" {
+		t.Fatalf("proof mismatch: diff (-want +got)\n%s", diff)
+	}
+	root := roots[sz]
+	if err := VerifyRangeInclusion(hasher, begin, end, sz, leafHashes[begin:end], proof, root); err != nil {
+		t.Fatalf("VerifyRangeInclusion: %v", err)
+	}
+	if diff := cmp.Diff(want, proof); diff != "" {
+		t.Fatalf("VerifyRangeInclusion modified the proof: diff (-want +got)\n%s", diff)
+	}
+	swapped := [][]byte{leafHashes[3], leafHashes[2], leafHashes[4]}
+	for _, p := range []struct {
+		desc       string
+		begin, end uint64
+		leaves     [][]byte
+		proof      [][]byte
+		root       []byte
+	}{
+		{"wrong root", begin, end, leafHashes[begin:end], proof, roots[sz-1]},
+		{"shifted range", begin + 1, end + 1, leafHashes[begin:end], proof, root},
+		{"swapped leaves", begin, end, swapped, proof, root},
+		{"missing leaf", begin, end, leafHashes[begin : end-1], proof, root},
+		{"extra leaf", begin, end - 1, leafHashes[begin:end], proof, root},
+		{"truncated proof", begin, end, leafHashes[begin:end], proof[1:], root},
+		{"trailing garbage", begin, end, leafHashes[begin:end], extend(proof, []byte{}), root},
+		{"short hash", begin, end, leafHashes[begin:end], extend(proof[:2], []byte("short")), root},
+		{"empty range", begin, begin, nil, proof, root},
+	} {
+		if err := VerifyRangeInclusion(hasher, p.begin, p.end, sz, p.leaves, p.proof, p.root); err == nil {
+			t.Errorf("VerifyRangeInclusion: incorrectly verified against: %s", p.desc)
+		}
+	}
+}
+
+func TestErrEmptyProofExpected(t *testing.T) {
+	root := sha256EmptyTreeHash
+	proof := [][]byte{sha256SomeHash}
+	for _, size := range []uint64{0, 1, 5} {
+		err := VerifyConsistency(hasher, size, size, proof, root, root)
+		if !errors.Is(err, ErrEmptyProofExpected) {
+			t.Errorf("VerifyConsistency(%d, %d): %v, want %v", size, size, err, ErrEmptyProofExpected)
+		}
+	}
+	if err := VerifyInclusion(hasher, 0, 1, sha256SomeHash, proof, root); !errors.Is(err, ErrEmptyProofExpected) {
+		t.Errorf("VerifyInclusion(0, 1): %v, want %v", err, ErrEmptyProofExpected)
+	}
+
+	// Other proof size mismatches are different errors.
+	if err := VerifyConsistency(hasher, 1, 2, nil, root, root); err == nil || errors.Is(err, ErrEmptyProofExpected) {
+		t.Errorf("VerifyConsistency(1, 2): %v, want a different error", err)
+	}
+	if err := VerifyInclusion(hasher, 0, 2, sha256SomeHash, nil, root); err == nil || errors.Is(err, ErrEmptyProofExpected) {
+		t.Errorf("VerifyInclusion(0, 2): %v, want a different error", err)
+	}
+}
+
+func TestVerifyInclusionBytes(t *testing.T) {
+	for i, p := range inclusionProofs[1:] {
+		t.Run(fmt.Sprintf("proof:%d", i), func(t *testing.T) {
+			leafHash := hasher.HashLeaf(leaves[p.leaf-1])
+			root := roots[p.size-1]
+			proofBytes := bytes.Join(p.proof, nil)
+			if err := VerifyInclusionBytes(hasher, p.leaf-1, p.size, leafHash, proofBytes, root); err != nil {
+				t.Errorf("VerifyInclusionBytes: %v", err)
+			}
+			if err := VerifyInclusionBytes(hasher, p.leaf-1, p.size, leafHash, proofBytes, sha256SomeHash); err == nil {
+				t.Error("VerifyInclusionBytes accepted a wrong root")
+			}
+			if err := VerifyInclusionBytes(hasher, p.leaf-1, p.size, leafHash, append(proofBytes, 0), root); err == nil {
+				t.Error("VerifyInclusionBytes accepted a partial hash")
+			}
+			if len(proofBytes) > 0 {
+				if err := VerifyInclusionBytes(hasher, p.leaf-1, p.size, leafHash, proofBytes[1:], root); err == nil {
+					t.Error("VerifyInclusionBytes accepted a truncated proof")
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyInclusionHex(t *testing.T) {
+	p := inclusionProofs[3]
+	index := p.leaf - 1
+	leafHex := hex.EncodeToString(hasher.HashLeaf(leaves[index]))
+	rootHex := hex.EncodeToString(roots[p.size-1])
+	proofHex := make([]string, len(p.proof))
+	for i, h := range p.proof {
+		proofHex[i] = hex.EncodeToString(h)
+	}
+	if err := VerifyInclusionHex(hasher, index, p.size, leafHex, proofHex, rootHex); err != nil {
+		t.Fatalf("VerifyInclusionHex: %v", err)
+	}
+
+	replace := func(i int, h string) []string {
+		res := append([]string{}, proofHex...)
+		res[i] = h
+		return res
+	}
+	for _, tc := range []struct {
+		desc             string
+		leafHex, rootHex string
+		proofHex         []string
+	}{
+		{desc: "wrong-root", leafHex: leafHex, rootHex: leafHex, proofHex: proofHex},
+		{desc: "bad-leaf", leafHex: "xyz", rootHex: rootHex, proofHex: proofHex},
+		{desc: "odd-leaf", leafHex: leafHex[1:], rootHex: rootHex, proofHex: proofHex},
+		{desc: "short-root", leafHex: leafHex, rootHex: rootHex[2:], proofHex: proofHex},
+		{desc: "bad-proof", leafHex: leafHex, rootHex: rootHex, proofHex: replace(1, "zz")},
+		{desc: "long-proof-hash", leafHex: leafHex, rootHex: rootHex, proofHex: replace(0, proofHex[0]+"00")},
+		{desc: "empty-proof-hash", leafHex: leafHex, rootHex: rootHex, proofHex: replace(0, "")},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := VerifyInclusionHex(hasher, index, p.size, tc.leafHex, tc.proofHex, tc.rootHex); err == nil {
+				t.Error("VerifyInclusionHex succeeded unexpectedly")
+			}
+		})
+	}
+}
+
+func TestVerifyInclusionWithEphem(t *testing.T) {
+	const size = uint64(40)
+	nodes, roots := buildTree(t, size)
+	for sz := uint64(1); sz <= size; sz++ {
+		for index := uint64(0); index < sz; index++ {
+			n, err := Inclusion(index, sz)
+			if err != nil {
+				t.Fatalf("Inclusion: %v", err)
+			}
+			proof, err := n.Rehash(getHashes(nodes, n.IDs), hasher.HashChildren)
+			if err != nil {
+				t.Fatalf("Rehash: %v", err)
+			}
+			leafHash := nodes[compact.NewNodeID(0, index)]
+
+			id, hash, err := VerifyInclusionWithEphem(hasher, index, sz, leafHash, proof, roots[sz])
+			if err != nil {
+				t.Fatalf("VerifyInclusionWithEphem(%d, %d): %v", index, sz, err)
+			}
+			wantID, begin, end := n.Ephem()
+			if id != wantID {
+				t.Errorf("VerifyInclusionWithEphem(%d, %d): got ID %+v, want %+v", index, sz, id, wantID)
+			}
+			// Compute the expected ephemeral node hash directly from the compact
+			// range of the leaves that it covers.
+			var wantHash []byte
+			if begin < end {
+				eBegin, _ := wantID.Coverage()
+				hashes := getHashes(nodes, compact.RangeNodes(eBegin, sz, nil))
+				wantHash = hashes[len(hashes)-1]
+				for i := len(hashes) - 2; i >= 0; i-- {
+					wantHash = hasher.HashChildren(hashes[i], wantHash)
+				}
+			}
+			if !bytes.Equal(hash, wantHash) {
+				t.Errorf("VerifyInclusionWithEphem(%d, %d): got hash %x, want %x", index, sz, hash, wantHash)
+			}
+
+			if _, _, err := VerifyInclusionWithEphem(hasher, index, sz, leafHash, proof, sha256SomeHash); err == nil {
+				t.Errorf("VerifyInclusionWithEphem(%d, %d): accepted wrong root", index, sz)
+			}
+		}
+	}
+}
+
+func TestVerifyInclusionWithEphemHash(t *testing.T) {
+	const size = uint64(40)
+	nodes, roots := buildTree(t, size)
+	for sz := uint64(1); sz <= size; sz++ {
+		for index := uint64(0); index < sz; index++ {
+			n, err := Inclusion(index, sz)
+			if err != nil {
+				t.Fatalf("Inclusion: %v", err)
+			}
+			proof, err := n.Rehash(getHashes(nodes, n.IDs), hasher.HashChildren)
+			if err != nil {
+				t.Fatalf("Rehash: %v", err)
+			}
+			leafHash := nodes[compact.NewNodeID(0, index)]
+			_, ephemHash, err := VerifyInclusionWithEphem(hasher, index, sz, leafHash, proof, roots[sz])
+			if err != nil {
+				t.Fatalf("VerifyInclusionWithEphem: %v", err)
+			}
+
+			// Cut the ephemeral node hash out of the proof.
+			pathProof := proof
+			if _, begin, end := n.Ephem(); begin < end {
+				pathProof = append(append([][]byte{}, proof[:begin]...), proof[begin+1:]...)
+			}
+			if err := VerifyInclusionWithEphemHash(hasher, index, sz, leafHash, pathProof, ephemHash, roots[sz]); err != nil {
+				t.Errorf("VerifyInclusionWithEphemHash(%d, %d): %v", index, sz, err)
+			}
+			if err := VerifyInclusionWithEphemHash(hasher, index, sz, leafHash, pathProof, ephemHash, sha256SomeHash); err == nil {
+				t.Errorf("VerifyInclusionWithEphemHash(%d, %d): accepted wrong root", index, sz)
+			}
+			if ephemHash == nil {
+				if err := VerifyInclusionWithEphemHash(hasher, index, sz, leafHash, pathProof, sha256SomeHash, roots[sz]); err == nil {
+					t.Errorf("VerifyInclusionWithEphemHash(%d, %d): accepted unexpected ephemeral hash", index, sz)
+				}
+				continue
+			}
+			if err := VerifyInclusionWithEphemHash(hasher, index, sz, leafHash, pathProof, sha256SomeHash, roots[sz]); err == nil {
+				t.Errorf("VerifyInclusionWithEphemHash(%d, %d): accepted wrong ephemeral hash", index, sz)
+			}
+			if err := VerifyInclusionWithEphemHash(hasher, index, sz, leafHash, pathProof, nil, roots[sz]); err == nil {
+				t.Errorf("VerifyInclusionWithEphemHash(%d, %d): accepted missing ephemeral hash", index, sz)
+			}
+			if err := VerifyInclusionWithEphemHash(hasher, index, sz, leafHash, proof, ephemHash, roots[sz]); err == nil {
+				t.Errorf("VerifyInclusionWithEphemHash(%d, %d): accepted a proof with the ephemeral hash", index, sz)
+			}
+		}
+	}
+}
+
+func TestConsistencyProofSize(t *testing.T) {
+	for _, p := range consistencyProofs {
+		if got, want := ConsistencyProofSize(p.size1, p.size2), len(p.proof); got != want {
+			t.Errorf("ConsistencyProofSize(%d, %d): got %d, want %d", p.size1, p.size2, got, want)
+		}
+	}
+	const size = uint64(100)
+	nodes, _ := buildTree(t, size)
+	for size2 := uint64(0); size2 <= size; size2++ {
+		for size1 := uint64(1); size1 <= size2; size1++ {
+			n, err := Consistency(size1, size2)
+			if err != nil {
+				t.Fatalf("Consistency: %v", err)
+			}
+			proof, err := n.Rehash(getHashes(nodes, n.IDs), hasher.HashChildren)
+			if err != nil {
+				t.Fatalf("Rehash: %v", err)
+			}
+			if got, want := ConsistencyProofSize(size1, size2), len(proof); got != want {
+				t.Errorf("ConsistencyProofSize(%d, %d): got %d, want %d", size1, size2, got, want)
+			}
+		}
+	}
+	for _, tc := range []struct {
+		size1, size2 uint64
+		want         int
+	}{
+		{size1: 0, size2: 0, want: 0},
+		{size1: 0, size2: 5, want: -1},
+		{size1: 6, size2: 5, want: -1},
+		{size1: 1<<63 + 1, size2: 1<<64 - 1, want: 65},
+	} {
+		if got := ConsistencyProofSize(tc.size1, tc.size2); got != tc.want {
+			t.Errorf("ConsistencyProofSize(%d, %d): got %d, want %d", tc.size1, tc.size2, got, tc.want)
+		}
+	}
+}
+
+// countingHasher is a LogHasher which counts HashChildren calls.
+type countingHasher struct {
+	merkle.LogHasher
+	calls int
+}
+
+func (h *countingHasher) HashChildren(l, r []byte) []byte {
+	h.calls++
+	return h.LogHasher.HashChildren(l, r)
+}
+
+func TestNeedsConsistencyProof(t *testing.T) {
+	for _, tc := range []struct {
+		size1, size2 uint64
+		want         bool
+	}{
+		{size1: 0, size2: 0, want: false},
+		{size1: 0, size2: 1, want: false},
+		{size1: 1, size2: 1, want: false},
+		{size1: 5, size2: 5, want: false},
+		{size1: 6, size2: 5, want: false},
+		{size1: 1, size2: 2, want: true},
+		{size1: 4, size2: 8, want: true},
+		{size1: 1<<64 - 2, size2: 1<<64 - 1, want: true},
+	} {
+		got := NeedsConsistencyProof(tc.size1, tc.size2)
+		if got != tc.want {
+			t.Errorf("NeedsConsistencyProof(%d, %d): got %v, want %v", tc.size1, tc.size2, got, tc.want)
+		}
+		// The proof must be non-empty exactly when it is needed.
+		if size := ConsistencyProofSize(tc.size1, tc.size2); got != (size > 0) {
+			t.Errorf("NeedsConsistencyProof(%d, %d) = %v, but proof size is %d", tc.size1, tc.size2, got, size)
+		}
+	}
+}
+
+func TestHashOps(t *testing.T) {
+	const size = uint64(70)
+	nodes, roots := buildTree(t, size)
+	for size2 := uint64(1); size2 <= size; size2++ {
+		for index := uint64(0); index < size2; index++ {
+			n, err := Inclusion(index, size2)
+			if err != nil {
+				t.Fatalf("Inclusion: %v", err)
+			}
+			proof, err := n.Rehash(getHashes(nodes, n.IDs), hasher.HashChildren)
+			if err != nil {
+				t.Fatalf("Rehash: %v", err)
+			}
+			ch := &countingHasher{LogHasher: hasher}
+			if err := VerifyInclusion(ch, index, size2, nodes[compact.NewNodeID(0, index)], proof, roots[size2]); err != nil {
+				t.Fatalf("VerifyInclusion: %v", err)
+			}
+			if got, want := InclusionHashOps(index, size2), ch.calls; got != want {
+				t.Errorf("InclusionHashOps(%d, %d): got %d, want %d", index, size2, got, want)
+			}
+		}
+		for size1 := uint64(0); size1 <= size2; size1++ {
+			n, err := Consistency(size1, size2)
+			if err != nil {
+				t.Fatalf("Consistency: %v", err)
+			}
+			proof, err := n.Rehash(getHashes(nodes, n.IDs), hasher.HashChildren)
+			if err != nil {
+				t.Fatalf("Rehash: %v", err)
+			}
+			ch := &countingHasher{LogHasher: hasher}
+			if err := VerifyConsistency(ch, size1, size2, proof, roots[size1], roots[size2]); err != nil && size1 != 0 {
+				t.Fatalf("VerifyConsistency(%d, %d): %v", size1, size2, err)
+			}
+			if got, want := ConsistencyHashOps(size1, size2), ch.calls; got != want {
+				t.Errorf("ConsistencyHashOps(%d, %d): got %d, want %d", size1, size2, got, want)
+			}
+		}
+	}
+	if got := InclusionHashOps(5, 5); got != 0 {
+		t.Errorf("InclusionHashOps(5, 5): got %d, want 0", got)
+	}
+	if got := ConsistencyHashOps(5, 4); got != 0 {
+		t.Errorf("ConsistencyHashOps(5, 4): got %d, want 0", got)
+	}
+}
+
+// buildTree returns all the perfect subtree hashes of a Merkle tree of the
+// given size, and its root hashes indexed by tree size.
+func buildTree(t testing.TB, size uint64) (map[compact.NodeID][]byte, [][]byte) {
+	t.Helper()
+	nodes := make(map[compact.NodeID][]byte)
+	visit := func(id compact.NodeID, hash []byte) { nodes[id] = hash }
+	roots := [][]byte{hasher.EmptyRoot()}
+
+	rf := &compact.RangeFactory{Hash: hasher.HashChildren}
+	cr := rf.NewEmptyRange(0)
+	for i := uint64(0); i < size; i++ {
+		if err := cr.Append(hasher.HashLeaf([]byte(fmt.Sprintf("leaf %d", i))), visit); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		root, err := cr.GetRootHash(nil)
+		if err != nil {
+			t.Fatalf("GetRootHash: %v", err)
+		}
+		roots = append(roots, root)
+	}
+	return nodes, roots
+}
+
+// getHashes returns the hashes of the given nodes.
+func getHashes(nodes map[compact.NodeID][]byte, ids []compact.NodeID) [][]byte {
+	hashes := make([][]byte, len(ids))
+	for i, id := range ids {
+		hashes[i] = nodes[id]
+	}
+	return hashes
+}
+
 // extend explicitly copies |proof| slice and appends |hashes| to it.
 func extend(proof [][]byte, hashes ...[]byte) [][]byte {
 	res := make([][]byte, len(proof), len(proof)+len(hashes))
@@ -403,3 +1227,39 @@ func dh(h string, expLen int) []byte {
 	}
 	return r
 }
+
+func BenchmarkVerifyConsistency(b *testing.B) {
+	for _, sizes := range []struct{ size1, size2 uint64 }{
+		{1000, 2000},
+		{1<<20 - 1, 1<<21 + 5},
+		{1<<40 - 12345, 1<<41 + 999},
+		{1<<62 + 1, 1<<63 - 1},
+	} {
+		size1, size2 := sizes.size1, sizes.size2
+		// The proof does not have to come from a real tree. Instead, derive the
+		// roots from an arbitrary proof of the correct length.
+		proof := make([][]byte, ConsistencyProofSize(size1, size2))
+		for i := range proof {
+			proof[i] = hasher.HashLeaf([]byte(fmt.Sprintf("proof %d", i)))
+		}
+		var mismatch RootMismatchError
+		_, err := RootFromConsistencyProof(hasher, size1, size2, proof, sha256SomeHash)
+		if !errors.As(err, &mismatch) {
+			b.Fatalf("RootFromConsistencyProof: %v, want RootMismatchError", err)
+		}
+		root1 := mismatch.CalculatedRoot
+		root2, err := RootFromConsistencyProof(hasher, size1, size2, proof, root1)
+		if err != nil {
+			b.Fatalf("RootFromConsistencyProof: %v", err)
+		}
+
+		b.Run(fmt.Sprintf("%d:%d", size1, size2), func(b *testing.B) {
+			b.ReportAllocs()
+			for n := 0; n < b.N; n++ {
+				if err := VerifyConsistency(hasher, size1, size2, proof, root1, root2); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
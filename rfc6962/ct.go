@@ -0,0 +1,67 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rfc6962
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Values of the enums used in the MerkleTreeLeaf structure, see RFC 6962
+// section 3.4.
+const (
+	v1              = 0 // Version.
+	timestampedType = 0 // MerkleLeafType.
+	x509EntryType   = 0 // LogEntryType.
+	precertType     = 1 // LogEntryType.
+)
+
+// MerkleTreeLeafForCert returns the serialized MerkleTreeLeaf structure, as
+// defined in RFC 6962 section 3.4, for an X.509 certificate entry with the
+// given timestamp (in milliseconds since the epoch), ASN.1 DER encoded
+// certificate, and CT extensions. The result can be passed to HashLeaf.
+func MerkleTreeLeafForCert(timestamp uint64, cert, extensions []byte) ([]byte, error) {
+	if len(cert) == 0 || len(cert) >= 1<<24 {
+		return nil, fmt.Errorf("certificate length %d out of range [1, 2^24)", len(cert))
+	}
+	return merkleTreeLeaf(timestamp, x509EntryType, nil, cert, extensions)
+}
+
+// MerkleTreeLeafForPrecert returns the serialized MerkleTreeLeaf structure, as
+// defined in RFC 6962 section 3.4, for a precertificate entry with the given
+// timestamp (in milliseconds since the epoch), SHA-256 hash of the issuer's
+// public key, DER encoded TBSCertificate, and CT extensions. The result can be
+// passed to HashLeaf.
+func MerkleTreeLeafForPrecert(timestamp uint64, issuerKeyHash [32]byte, tbsCert, extensions []byte) ([]byte, error) {
+	if len(tbsCert) == 0 || len(tbsCert) >= 1<<24 {
+		return nil, fmt.Errorf("TBSCertificate length %d out of range [1, 2^24)", len(tbsCert))
+	}
+	return merkleTreeLeaf(timestamp, precertType, issuerKeyHash[:], tbsCert, extensions)
+}
+
+func merkleTreeLeaf(timestamp uint64, entryType uint16, prefix, cert, extensions []byte) ([]byte, error) {
+	if len(extensions) >= 1<<16 {
+		return nil, fmt.Errorf("extensions length %d out of range [0, 2^16)", len(extensions))
+	}
+	b := make([]byte, 0, 2+8+2+len(prefix)+3+len(cert)+2+len(extensions))
+	b = append(b, v1, timestampedType)
+	b = binary.BigEndian.AppendUint64(b, timestamp)
+	b = binary.BigEndian.AppendUint16(b, entryType)
+	b = append(b, prefix...)
+	b = append(b, byte(len(cert)>>16), byte(len(cert)>>8), byte(len(cert)))
+	b = append(b, cert...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(extensions)))
+	return append(b, extensions...), nil
+}
@@ -0,0 +1,206 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rfc6962
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// TestMerkleTreeLeaf checks the leaf serialization against synthetic entries,
+// not ones taken from a real CT log. The expected leaf is spelled out field by
+// field following the RFC 6962 section 3.4 layout, and the expected hash is
+// computed independently of this package, with the command in the comment.
+// See TestMerkleTreeLeafForRealPrecert for entries logged by real CT logs.
+func TestMerkleTreeLeaf(t *testing.T) {
+	const timestamp = 1234567890123 // 0x0000011f71fb04cb
+	var keyHash [32]byte
+	for i := range keyHash {
+		keyHash[i] = 0xab
+	}
+
+	for _, tc := range []struct {
+		desc     string
+		leaf     func() ([]byte, error)
+		wantLeaf string
+		wantHash string
+	}{
+		// echo -n 00${wantLeaf} | xxd -r -p | sha256sum
+		{
+			desc: "cert",
+			leaf: func() ([]byte, error) {
+				return MerkleTreeLeafForCert(timestamp, []byte("cert"), nil)
+			},
+			wantLeaf: "0000" + "0000011f71fb04cb" + "0000" + "000004" + "63657274" + "0000",
+			wantHash: "0e5150da55ac3c2ae510dbcfae771c684d8d6ced94fcc7158f7b394c9374ada7",
+		},
+		// echo -n 00${wantLeaf} | xxd -r -p | sha256sum
+		{
+			desc: "precert",
+			leaf: func() ([]byte, error) {
+				return MerkleTreeLeafForPrecert(timestamp, keyHash, []byte("tbs"), []byte{1, 2})
+			},
+			wantLeaf: "0000" + "0000011f71fb04cb" + "0001" + hex.EncodeToString(keyHash[:]) +
+				"000003" + "746273" + "0002" + "0102",
+			wantHash: "a9dc5e7e21e5a5a9a5662b702bc9c5bc27f97d7dd491fd2ac9accbb1425b46b6",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			leaf, err := tc.leaf()
+			if err != nil {
+				t.Fatalf("MerkleTreeLeaf: %v", err)
+			}
+			if got, want := hex.EncodeToString(leaf), tc.wantLeaf; got != want {
+				t.Errorf("leaf: got %s, want %s", got, want)
+			}
+			wantHash, err := hex.DecodeString(tc.wantHash)
+			if err != nil {
+				t.Fatalf("hex.DecodeString(%x): %v", tc.wantHash, err)
+			}
+			if got := DefaultHasher.HashLeaf(leaf); !bytes.Equal(got, wantHash) {
+				t.Errorf("leaf hash: got %x, want %x", got, wantHash)
+			}
+		})
+	}
+}
+
+// googlePrecertTBS is the TBSCertificate of the precertificate logged for the
+// www.google.com leaf certificate in Go's crypto/x509 verify_test.go, i.e. of
+// the final certificate with the SCT list extension removed.
+const googlePrecertTBS = `
+	30820332a0030201020210111991593cd5a33d1231ea33c3644cdc300d06092a
+	864886f70d01010b05003046310b300906035504061302555331223020060355
+	040a1319476f6f676c65205472757374205365727669636573204c4c43311330
+	110603550403130a47545320434120314333301e170d32333031303230383139
+	31395a170d3233303332373038313931385a3019311730150603550403130e77
+	77772e676f6f676c652e636f6d30820122300d06092a864886f70d0101010500
+	0382010f003082010a0282010100ab7d2876b28c4f9e1326290c28bf12fa5c28
+	08c4f981e3f4bbda568e4e9ac1d07a8b790ef941388a30809a16dc64a78a68b9
+	c6277f307993700756b269b098c97ee10e20bf9d1719538f4b5f9fa8e7990355
+	a367d7860a920da409e5f6e470f14b068eb0d0085ca3d3aa240be4b3ef7384f0
+	865f07639d6184d590ad312b37bef97986e2c4450bbf254bab904b6c23a93e8f
+	27d3f3138d0df692ed6342adc62183090bf45fd955b976817ab7dcec2a579f94
+	037c39d2a9d0c13d3878344f53f7cb26faed3ee7d12a62b076de1b4e8099070a
+	245f60ffd54f26d45fe25d505913c30bee8952acd70b100698844667b4cd3313
+	cc056ffb0d15fac3ca0b29535bd30203010001a382015f3082015b300e060355
+	1d0f0101ff0404030205a030130603551d25040c300a06082b06010505070301
+	300c0603551d130101ff04023000301d0603551d0e0416041419dba23de57fdf
+	976ade28120a3a66d20cafc66e301f0603551d230418301680148a747faf85cd
+	ee95cd3d9cd0e24614f371351d27306a06082b06010505070101045e305c3027
+	06082b06010505073001861b687474703a2f2f6f6373702e706b692e676f6f67
+	2f677473316333303106082b060105050730028625687474703a2f2f706b692e
+	676f6f672f7265706f2f63657274732f6774733163332e64657230190603551d
+	1104123010820e7777772e676f6f676c652e636f6d30210603551d20041a3018
+	3008060667810c010201300c060a2b06010401d679020503303c0603551d1f04
+	3530333031a02fa02d862b687474703a2f2f63726c732e706b692e676f6f672f
+	6774733163332f514f764a304e31735432412e63726c`
+
+// TestMerkleTreeLeafForRealPrecert checks the precertificate leaf encoding
+// against the SCTs embedded into a real certificate. A log signs the same bytes
+// as the MerkleTreeLeaf structure, so a valid SCT signature confirms that the
+// leaf is encoded exactly as the log did. The log keys are confirmed by their
+// SHA-256 hashes, which are the log IDs in the SCTs.
+func TestMerkleTreeLeafForRealPrecert(t *testing.T) {
+	tbs := decodeHex(t, googlePrecertTBS)
+	// SHA-256 of the SubjectPublicKeyInfo of the issuer, GTS CA 1C3.
+	var issuerKeyHash [32]byte
+	copy(issuerKeyHash[:], decodeHex(t, "cc24e77cbc0b29b4bd4b6b1ba7eb85cf82993a8705bd7c64574e827bd3b9336c"))
+
+	for _, tc := range []struct {
+		logID     string
+		logKey    string
+		timestamp uint64
+		sig       string
+		wantHash  string
+	}{
+		{
+			logID: "7a328c54d8b72db620ea38e0521ee98416703213854d3bd22bc13a57a352eb52",
+			logKey: "3059301306072a8648ce3d020106082a8648ce3d030107034200048bff2d9218cb469d" +
+				"125eb959753ccd91377a1ea99c997883273ddf01d58b80e8639afe26a21bd18705ee97d6" +
+				"e05b4383811c02f54180807fefa461cfbc84b5a8",
+			timestamp: 1672651160101,
+			sig: "30450220054922914217768f92dd3f0f3f4fdcbe8921525ba6444dc645618be1644c2e97" +
+				"022100d8f9bc0adfce26671c97199e65c0d45300f3734fc171d7efff9db6862b705030",
+			wantHash: "b59f927d2a54338c04df696c6e10330d7a43173d1bf14bd08d4f81bbf91d1a29",
+		},
+		{
+			logID: "e83ed0da3ef5063532e75728bc896bc903d3cbd1116beceb69e1777d6d06bd6e",
+			logKey: "3059301306072a8648ce3d020106082a8648ce3d03010703420004d0908f64524e42ac" +
+				"84b62e4cf23d7700b377080547aa454ce32c8e70a582bb6cb27b9c987aa0e911762800b2" +
+				"20b4cdd3987b4d9627e6b7ee226ad1b02e917778",
+			timestamp: 1672651160052,
+			sig: "304502202e857274d7cc14a57d228651f8cd14b89db33aa6f1211ba6ed29fd44c3ce0463" +
+				"022100f5fc18330920d3bd8bdca3a92907a89f2926e0e6071be4a4e7631418c115b5e1",
+			wantHash: "454757815638909db97a424cff0062ae54c6619c83b8556bef80e154432071f8",
+		},
+	} {
+		t.Run(tc.logID[:8], func(t *testing.T) {
+			keyDER := decodeHex(t, tc.logKey)
+			if got := sha256.Sum256(keyDER); !bytes.Equal(got[:], decodeHex(t, tc.logID)) {
+				t.Fatalf("log key hash: got %x, want %s", got, tc.logID)
+			}
+			key, err := x509.ParsePKIXPublicKey(keyDER)
+			if err != nil {
+				t.Fatalf("ParsePKIXPublicKey: %v", err)
+			}
+
+			leaf, err := MerkleTreeLeafForPrecert(tc.timestamp, issuerKeyHash, tbs, nil)
+			if err != nil {
+				t.Fatalf("MerkleTreeLeafForPrecert: %v", err)
+			}
+			digest := sha256.Sum256(leaf)
+			if !ecdsa.VerifyASN1(key.(*ecdsa.PublicKey), digest[:], decodeHex(t, tc.sig)) {
+				t.Error("SCT signature does not verify over the leaf")
+			}
+			if got, want := DefaultHasher.HashLeaf(leaf), decodeHex(t, tc.wantHash); !bytes.Equal(got, want) {
+				t.Errorf("leaf hash: got %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		t.Fatalf("hex.DecodeString(%q): %v", s, err)
+	}
+	return b
+}
+
+func TestMerkleTreeLeafErrors(t *testing.T) {
+	for _, tc := range []struct {
+		desc       string
+		cert, exts []byte
+	}{
+		{desc: "empty-cert", cert: nil},
+		{desc: "long-cert", cert: make([]byte, 1<<24)},
+		{desc: "long-extensions", cert: []byte("cert"), exts: make([]byte, 1<<16)},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if _, err := MerkleTreeLeafForCert(0, tc.cert, tc.exts); err == nil {
+				t.Error("MerkleTreeLeafForCert succeeded unexpectedly")
+			}
+			if _, err := MerkleTreeLeafForPrecert(0, [32]byte{}, tc.cert, tc.exts); err == nil {
+				t.Error("MerkleTreeLeafForPrecert succeeded unexpectedly")
+			}
+		})
+	}
+}
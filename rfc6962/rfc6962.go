@@ -18,6 +18,12 @@ package rfc6962
 import (
 	"crypto"
 	_ "crypto/sha256" // SHA256 is the default algorithm.
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/transparency-dev/merkle/compact"
 )
 
 // Domain separation prefixes
@@ -39,6 +45,33 @@ func New(h crypto.Hash) *Hasher {
 	return &Hasher{Hash: h}
 }
 
+// LengthPrefixedHasher is a variant of the RFC6962 Hasher in which leaf data
+// is length-prefixed before hashing, as used by some experimental logs. The
+// leaf hash is computed over: the LeafHashPrefix byte, followed by the 8-byte
+// big-endian length of the leaf data, followed by the leaf data. The empty
+// root and interior node hashes are the same as in RFC6962.
+type LengthPrefixedHasher struct {
+	Hasher
+}
+
+// NewLengthPrefixed creates a new LengthPrefixedHasher on the passed in hash
+// function.
+func NewLengthPrefixed(h crypto.Hash) *LengthPrefixedHasher {
+	return &LengthPrefixedHasher{Hasher: Hasher{Hash: h}}
+}
+
+// HashLeaf returns the Merkle tree leaf hash of the length-prefixed data passed
+// in through leaf.
+func (t *LengthPrefixedHasher) HashLeaf(leaf []byte) []byte {
+	h := t.New()
+	var prefix [9]byte
+	prefix[0] = RFC6962LeafHashPrefix
+	binary.BigEndian.PutUint64(prefix[1:], uint64(len(leaf)))
+	h.Write(prefix[:])
+	h.Write(leaf)
+	return h.Sum(nil)
+}
+
 // EmptyRoot returns a special case for an empty tree.
 func (t *Hasher) EmptyRoot() []byte {
 	return t.New().Sum(nil)
@@ -66,3 +99,62 @@ func (t *Hasher) HashChildren(l, r []byte) []byte {
 	h.Write(b)
 	return h.Sum(nil)
 }
+
+// HashChildrenN returns the hash of an inner node with the given children,
+// computed over NodeHashPrefix followed by the concatenation of the children
+// hashes. The children are written to the hash function one by one, so wide
+// nodes are hashed without building the concatenation in memory. For two
+// children this is equivalent to HashChildren.
+//
+// This is groundwork for experimenting with wider trees on top of this
+// package. Note that the trees, compact ranges and proofs in this module are
+// binary, and only ever use HashChildren.
+func (t *Hasher) HashChildrenN(children ...[]byte) []byte {
+	h := t.New()
+	h.Write([]byte{RFC6962NodeHashPrefix})
+	for _, c := range children {
+		h.Write(c)
+	}
+	return h.Sum(nil)
+}
+
+// RootFromSubtrees returns the root hash of a Merkle tree given the hashes of
+// its perfect subtrees, i.e. the nodes returned by compact.RangeNodes(0, size),
+// ordered from left to right. Returns the empty tree root if the list is empty.
+func (t *Hasher) RootFromSubtrees(hashes [][]byte) []byte {
+	if len(hashes) == 0 {
+		return t.EmptyRoot()
+	}
+	root := hashes[len(hashes)-1]
+	for i := len(hashes) - 2; i >= 0; i-- {
+		root = t.HashChildren(hashes[i], root)
+	}
+	return root
+}
+
+// MerkleRootFromReader computes the root hash of the Merkle tree with leaf
+// hashes read from r. The reader must contain a concatenation of leaf hashes,
+// each of which is Size() bytes long. The leaf hashes are folded into a
+// compact range as they are read, so memory usage is logarithmic in the number
+// of leaves. Returns an error if the data ends with a partial leaf hash.
+func (t *Hasher) MerkleRootFromReader(r io.Reader) ([]byte, error) {
+	rf := &compact.RangeFactory{Hash: t.HashChildren}
+	cr := rf.NewEmptyRange(0)
+	for {
+		hash := make([]byte, t.Size())
+		if _, err := io.ReadFull(r, hash); errors.Is(err, io.EOF) {
+			break
+		} else if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, fmt.Errorf("partial leaf hash at index %d", cr.End())
+		} else if err != nil {
+			return nil, err
+		}
+		if err := cr.Append(hash, nil); err != nil {
+			return nil, err
+		}
+	}
+	if cr.End() == 0 {
+		return t.EmptyRoot(), nil
+	}
+	return cr.GetRootHash(nil)
+}
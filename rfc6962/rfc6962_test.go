@@ -16,8 +16,16 @@ package rfc6962
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/testonly"
 )
 
 func TestRFC6962Hasher(t *testing.T) {
@@ -69,6 +77,50 @@ func TestRFC6962Hasher(t *testing.T) {
 	}
 }
 
+func TestLengthPrefixedHasher(t *testing.T) {
+	hasher := NewLengthPrefixed(crypto.SHA256)
+	for _, tc := range []struct {
+		desc string
+		got  []byte
+		want string
+	}{
+		// echo -n | sha256sum
+		{
+			desc: "Empty",
+			want: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			got:  hasher.EmptyRoot(),
+		},
+		// echo -n 000000000000000000 | xxd -r -p | sha256sum
+		{
+			desc: "Empty Leaf",
+			want: "3e7077fd2f66d689e0cee6a7cf5b37bf2dca7c979af356d0a31cbc5c85605c7d",
+			got:  hasher.HashLeaf([]byte{}),
+		},
+		// echo -n 0000000000000000074C313233343536 | xxd -r -p | sha256sum
+		{
+			desc: "Leaf",
+			want: "8d3e1695fef913c5efe51263b2eb640c39d70cd9a203c0f372177dd0bf7e003d",
+			got:  hasher.HashLeaf([]byte("L123456")),
+		},
+		// echo -n 014E3132334E343536 | xxd -r -p | sha256sum
+		{
+			desc: "Node",
+			want: "aa217fe888e47007fa15edab33c2b492a722cb106c64667fc2b044444de66bbb",
+			got:  hasher.HashChildren([]byte("N123"), []byte("N456")),
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			wantBytes, err := hex.DecodeString(tc.want)
+			if err != nil {
+				t.Fatalf("hex.DecodeString(%x): %v", tc.want, err)
+			}
+			if got, want := tc.got, wantBytes; !bytes.Equal(got, want) {
+				t.Errorf("got %x, want %x", got, want)
+			}
+		})
+	}
+}
+
 // TODO(pavelkalinnikov): Apply this test to all LogHasher implementations.
 func TestRFC6962HasherCollisions(t *testing.T) {
 	hasher := DefaultHasher
@@ -97,6 +149,91 @@ func TestRFC6962HasherCollisions(t *testing.T) {
 	}
 }
 
+func TestMerkleRootFromReader(t *testing.T) {
+	hasher := DefaultHasher
+	for _, size := range []uint64{0, 1, 2, 3, 7, 8, 100, 1025} {
+		t.Run(fmt.Sprintf("size:%d", size), func(t *testing.T) {
+			tree := testonly.New(hasher)
+			var data []byte
+			for i := uint64(0); i < size; i++ {
+				hash := hasher.HashLeaf([]byte(fmt.Sprintf("leaf %d", i)))
+				tree.Append(hash)
+				data = append(data, hash...)
+			}
+			path := filepath.Join(t.TempDir(), "hashes")
+			if err := os.WriteFile(path, data, 0o644); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer f.Close()
+
+			got, err := hasher.MerkleRootFromReader(f)
+			if err != nil {
+				t.Fatalf("MerkleRootFromReader: %v", err)
+			}
+			if want := tree.Hash(); !bytes.Equal(got, want) {
+				t.Errorf("MerkleRootFromReader: got %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+func TestMerkleRootFromReaderPartial(t *testing.T) {
+	hasher := DefaultHasher
+	data := append(hasher.HashLeaf([]byte("leaf")), 1, 2, 3)
+	if _, err := hasher.MerkleRootFromReader(bytes.NewReader(data)); err == nil {
+		t.Error("MerkleRootFromReader succeeded unexpectedly")
+	}
+}
+
+func TestRootFromSubtrees(t *testing.T) {
+	hasher := DefaultHasher
+	if got, want := hasher.RootFromSubtrees(nil), hasher.EmptyRoot(); !bytes.Equal(got, want) {
+		t.Errorf("RootFromSubtrees(nil): got %x, want %x", got, want)
+	}
+
+	rf := &compact.RangeFactory{Hash: hasher.HashChildren}
+	cr := rf.NewEmptyRange(0)
+	for size := uint64(1); size <= 100; size++ {
+		if err := cr.Append(hasher.HashLeaf([]byte(fmt.Sprintf("leaf %d", size))), nil); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		want, err := cr.GetRootHash(nil)
+		if err != nil {
+			t.Fatalf("GetRootHash: %v", err)
+		}
+		if got := hasher.RootFromSubtrees(cr.Hashes()); !bytes.Equal(got, want) {
+			t.Errorf("RootFromSubtrees(size %d): got %x, want %x", size, got, want)
+		}
+	}
+}
+
+func TestHashChildrenN(t *testing.T) {
+	hasher := DefaultHasher
+	h := func(s string) []byte { return hasher.HashLeaf([]byte(s)) }
+	a, b, c, d := h("a"), h("b"), h("c"), h("d")
+
+	if got, want := hasher.HashChildrenN(a, b), hasher.HashChildren(a, b); !bytes.Equal(got, want) {
+		t.Errorf("HashChildrenN(a, b): got %x, want %x", got, want)
+	}
+
+	want := sha256.Sum256(append(append(append(append([]byte{RFC6962NodeHashPrefix}, a...), b...), c...), d...))
+	got := hasher.HashChildrenN(a, b, c, d)
+	if !bytes.Equal(got, want[:]) {
+		t.Errorf("HashChildrenN(a, b, c, d): got %x, want %x", got, want)
+	}
+	// A 4-ary node differs from the root of the equivalent binary subtree.
+	if binary := hasher.HashChildren(hasher.HashChildren(a, b), hasher.HashChildren(c, d)); bytes.Equal(got, binary) {
+		t.Error("HashChildrenN(a, b, c, d) matches the binary subtree root")
+	}
+	if bytes.Equal(got, hasher.HashChildrenN(a, b, d, c)) {
+		t.Error("HashChildrenN does not depend on the children order")
+	}
+}
+
 func BenchmarkHashChildren(b *testing.B) {
 	h := DefaultHasher
 	l := h.HashLeaf([]byte("one"))
@@ -0,0 +1,46 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testonly
+
+import (
+	"math/rand/v2"
+
+	"github.com/transparency-dev/merkle"
+)
+
+// GenTree returns a Merkle tree of the given size, with random leaf entries
+// generated deterministically from the given seed. The returned tree provides
+// the root hash, leaf hashes, and inclusion and consistency proofs, which can
+// be used for property-testing proof verifiers. For testing only.
+func GenTree(hasher merkle.LogHasher, seed int64, size uint64) *Tree {
+	tree := New(hasher)
+	tree.AppendData(randomEntries(seed, size)...)
+	return tree
+}
+
+// randomEntries returns the given number of entries of random length and
+// content, generated deterministically from the given seed.
+func randomEntries(seed int64, size uint64) [][]byte {
+	rnd := rand.New(rand.NewPCG(uint64(seed), 0))
+	entries := make([][]byte, size)
+	for i := range entries {
+		entry := make([]byte, rnd.IntN(64))
+		for j := range entry {
+			entry[j] = byte(rnd.Uint32())
+		}
+		entries[i] = entry
+	}
+	return entries
+}
@@ -0,0 +1,62 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testonly
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/transparency-dev/merkle/proof"
+	"github.com/transparency-dev/merkle/rfc6962"
+)
+
+func TestGenTree(t *testing.T) {
+	hasher := rfc6962.DefaultHasher
+	for _, size := range []uint64{0, 1, 7, 64, 100} {
+		t.Run(fmt.Sprintf("size:%d", size), func(t *testing.T) {
+			tree := GenTree(hasher, 42, size)
+			if got, want := tree.Size(), size; got != want {
+				t.Fatalf("Size: got %d, want %d", got, want)
+			}
+			if got, want := tree.Hash(), GenTree(hasher, 42, size).Hash(); !bytes.Equal(got, want) {
+				t.Errorf("Hash is not deterministic: %x, want %x", got, want)
+			}
+			if size > 1 && bytes.Equal(tree.Hash(), GenTree(hasher, 43, size).Hash()) {
+				t.Error("Hash does not depend on the seed")
+			}
+
+			root := tree.Hash()
+			for i := uint64(0); i < size; i++ {
+				p, err := tree.InclusionProof(i, size)
+				if err != nil {
+					t.Fatalf("InclusionProof: %v", err)
+				}
+				if err := proof.VerifyInclusion(hasher, i, size, tree.LeafHash(i), p, root); err != nil {
+					t.Errorf("VerifyInclusion(%d): %v", i, err)
+				}
+			}
+			for size1 := uint64(1); size1 <= size; size1++ {
+				p, err := tree.ConsistencyProof(size1, size)
+				if err != nil {
+					t.Fatalf("ConsistencyProof: %v", err)
+				}
+				if err := proof.VerifyConsistency(hasher, size1, size, p, tree.HashAt(size1), root); err != nil {
+					t.Errorf("VerifyConsistency(%d, %d): %v", size1, size, err)
+				}
+			}
+		})
+	}
+}
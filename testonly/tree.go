@@ -15,21 +15,64 @@
 package testonly
 
 import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+
 	"github.com/transparency-dev/merkle"
 	"github.com/transparency-dev/merkle/compact"
 	"github.com/transparency-dev/merkle/proof"
 )
 
+// NodeStore stores the hashes of the perfect subtrees of a Tree.
+type NodeStore interface {
+	// Get returns the hash of the given node, which has been set before.
+	Get(id compact.NodeID) []byte
+	// Set sets the hash of the given node.
+	Set(id compact.NodeID, hash []byte)
+}
+
+// memStore is the default in-memory NodeStore.
+type memStore struct {
+	hashes [][][]byte // Node hashes, indexed by node (level, index).
+}
+
+func (m *memStore) Get(id compact.NodeID) []byte {
+	return m.hashes[id.Level][id.Index]
+}
+
+func (m *memStore) Set(id compact.NodeID, hash []byte) {
+	for uint(len(m.hashes)) <= id.Level {
+		m.hashes = append(m.hashes, nil)
+	}
+	if row := m.hashes[id.Level]; id.Index < uint64(len(row)) {
+		row[id.Index] = hash
+	} else if id.Index == uint64(len(row)) {
+		m.hashes[id.Level] = append(row, hash)
+	} else {
+		panic("gap in tree appends")
+	}
+}
+
 // Tree implements an append-only Merkle tree. For testing.
 type Tree struct {
 	hasher merkle.LogHasher
 	size   uint64
-	hashes [][][]byte // Node hashes, indexed by node (level, index).
+	store  NodeStore
 }
 
-// New returns a new empty Merkle tree.
+// New returns a new empty Merkle tree. The hasher's HashLeaf method is used
+// only by AppendData, so trees with a custom leaf hashing scheme can be built by
+// overriding this method in a wrapper of a standard hasher.
 func New(hasher merkle.LogHasher) *Tree {
-	return &Tree{hasher: hasher}
+	return NewWithStore(hasher, &memStore{})
+}
+
+// NewWithStore returns a new empty Merkle tree like New, which keeps the node
+// hashes in the given store. The store must be empty, and not be modified by
+// anything other than the returned Tree.
+func NewWithStore(hasher merkle.LogHasher, store NodeStore) *Tree {
+	return &Tree{hasher: hasher, store: store}
 }
 
 // AppendData adds the leaf hashes of the given entries to the end of the tree.
@@ -47,19 +90,13 @@ func (t *Tree) Append(hashes ...[]byte) {
 }
 
 func (t *Tree) appendImpl(hash []byte) {
-	level := 0
+	level := uint(0)
 	for ; (t.size>>level)&1 == 1; level++ {
-		row := append(t.hashes[level], hash)
-		hash = t.hasher.HashChildren(row[len(row)-2], hash)
-		t.hashes[level] = row
+		index := t.size >> level
+		t.store.Set(compact.NewNodeID(level, index), hash)
+		hash = t.hasher.HashChildren(t.store.Get(compact.NewNodeID(level, index-1)), hash)
 	}
-	if level > len(t.hashes) {
-		panic("gap in tree appends")
-	} else if level == len(t.hashes) {
-		t.hashes = append(t.hashes, nil)
-	}
-
-	t.hashes[level] = append(t.hashes[level], hash)
+	t.store.Set(compact.NewNodeID(level, t.size>>level), hash)
 	t.size++
 }
 
@@ -71,7 +108,7 @@ func (t *Tree) Size() uint64 {
 // LeafHash returns the leaf hash at the given index.
 // Requires 0 <= index < Size(), otherwise panics.
 func (t *Tree) LeafHash(index uint64) []byte {
-	return t.hashes[0][index]
+	return t.store.Get(compact.NewNodeID(0, index))
 }
 
 // Hash returns the current root hash of the tree.
@@ -115,10 +152,71 @@ func (t *Tree) ConsistencyProof(size1, size2 uint64) ([][]byte, error) {
 	return nodes.Rehash(t.getNodes(nodes.IDs), t.hasher.HashChildren)
 }
 
+// Snapshot returns a serialized copy of the tree, which can be loaded with
+// Restore. This allows building a big tree once, and cheaply reusing it in
+// multiple tests.
+//
+// The format is: the 8-byte big-endian tree size, followed by hashes of all
+// perfect subtrees ordered by (level, index).
+func (t *Tree) Snapshot() []byte {
+	hashSize := t.hasher.Size()
+	var count uint64
+	for level := uint(0); level < uint(bits.Len64(t.size)); level++ {
+		count += t.size >> level
+	}
+	data := make([]byte, 8, 8+count*uint64(hashSize))
+	binary.BigEndian.PutUint64(data, t.size)
+	for level := uint(0); level < uint(bits.Len64(t.size)); level++ {
+		for index, end := uint64(0), t.size>>level; index < end; index++ {
+			data = append(data, t.store.Get(compact.NewNodeID(level, index))...)
+		}
+	}
+	return data
+}
+
+// Restore loads into the tree a snapshot returned by the Snapshot method of a
+// tree using the same hasher. The tree must be empty, otherwise an error is
+// returned.
+func (t *Tree) Restore(data []byte) error {
+	if t.size != 0 {
+		return fmt.Errorf("can not restore into a non-empty tree of size %d", t.size)
+	}
+	if len(data) < 8 {
+		return fmt.Errorf("snapshot too short: %d bytes", len(data))
+	}
+	size := binary.BigEndian.Uint64(data)
+	data = data[8:]
+
+	hashSize := t.hasher.Size()
+	// The snapshot has at least size hashes. Checking this first also ensures
+	// that the total count below does not overflow.
+	if size > uint64(len(data)/hashSize) {
+		return fmt.Errorf("snapshot too short: %d bytes for tree size %d", len(data), size)
+	}
+	var count uint64
+	for level := uint(0); level < uint(bits.Len64(size)); level++ {
+		count += size >> level
+	}
+	if count > uint64(len(data)/hashSize) {
+		return fmt.Errorf("snapshot too short: %d bytes for tree size %d", len(data), size)
+	} else if rest := uint64(len(data)) - count*uint64(hashSize); rest != 0 {
+		return fmt.Errorf("snapshot has %d trailing bytes", rest)
+	}
+	for level := uint(0); level < uint(bits.Len64(size)); level++ {
+		for index, end := uint64(0), size>>level; index < end; index++ {
+			var hash []byte
+			hash, data = append([]byte(nil), data[:hashSize]...), data[hashSize:]
+			t.store.Set(compact.NewNodeID(level, index), hash)
+		}
+	}
+	t.size = size
+	return nil
+}
+
 func (t *Tree) getNodes(ids []compact.NodeID) [][]byte {
 	hashes := make([][]byte, len(ids))
 	for i, id := range ids {
-		hashes[i] = t.hashes[id.Level][id.Index]
+		hashes[i] = t.store.Get(id)
 	}
 	return hashes
 }
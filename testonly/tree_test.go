@@ -16,13 +16,16 @@ package testonly
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
 	"fmt"
 	"math/rand/v2"
-	"strconv"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/transparency-dev/merkle/compact"
+	"github.com/transparency-dev/merkle/proof"
 	"github.com/transparency-dev/merkle/rfc6962"
 )
 
@@ -167,7 +170,7 @@ func TestTreeAppend(t *testing.T) {
 		mt2.Append(rfc6962.DefaultHasher.HashLeaf(entry))
 	}
 
-	if diff := cmp.Diff(mt1, mt2, cmp.AllowUnexported(Tree{})); diff != "" {
+	if diff := cmp.Diff(mt1, mt2, cmp.AllowUnexported(Tree{}, memStore{})); diff != "" {
 		t.Errorf("Trees built with AppendData and Append mismatch: diff (-mt1 +mt2)\n%s", diff)
 	}
 }
@@ -182,22 +185,193 @@ func TestTreeAppendAssociativity(t *testing.T) {
 		mt2.AppendData(entry)
 	}
 
-	if diff := cmp.Diff(mt1, mt2, cmp.AllowUnexported(Tree{})); diff != "" {
+	if diff := cmp.Diff(mt1, mt2, cmp.AllowUnexported(Tree{}, memStore{})); diff != "" {
 		t.Errorf("AppendData is not associative: diff (-mt1 +mt2)\n%s", diff)
 	}
 }
 
+// versionedHasher is an RFC 6962 hasher which prepends a version byte before
+// the leaf hash domain separation prefix, i.e. it hashes leaves as
+// SHA-256(0x01 || 0x00 || leaf).
+type versionedHasher struct {
+	*rfc6962.Hasher
+}
+
+func (h versionedHasher) HashLeaf(leaf []byte) []byte {
+	hash := sha256.Sum256(append([]byte{1, rfc6962.RFC6962LeafHashPrefix}, leaf...))
+	return hash[:]
+}
+
+func TestTreeCustomLeafHash(t *testing.T) {
+	vh := versionedHasher{rfc6962.DefaultHasher}
+	entries := genEntries(50)
+	mt := New(vh)
+	mt.AppendData(entries...)
+
+	rf := &compact.RangeFactory{Hash: rfc6962.DefaultHasher.HashChildren, LeafHash: vh.HashLeaf}
+	cr := rf.NewEmptyRange(0)
+	for _, entry := range entries {
+		if err := cr.AppendLeaf(entry, nil); err != nil {
+			t.Fatalf("AppendLeaf: %v", err)
+		}
+	}
+	root, err := cr.GetRootHash(nil)
+	if err != nil {
+		t.Fatalf("GetRootHash: %v", err)
+	}
+	if got, want := mt.Hash(), root; !bytes.Equal(got, want) {
+		t.Fatalf("Hash: %x, want %x", got, want)
+	}
+	if bytes.Equal(root, newTree(entries).Hash()) {
+		t.Fatal("Hash matches the tree with default leaf hashing")
+	}
+
+	// Interior nodes use RFC 6962 hashing, so the standard hasher can verify
+	// proofs given the custom leaf hashes.
+	size := mt.Size()
+	for i, entry := range entries {
+		index := uint64(i)
+		p, err := mt.InclusionProof(index, size)
+		if err != nil {
+			t.Fatalf("InclusionProof: %v", err)
+		}
+		if err := proof.VerifyInclusion(rfc6962.DefaultHasher, index, size, vh.HashLeaf(entry), p, root); err != nil {
+			t.Errorf("VerifyInclusion(%d): %v", index, err)
+		}
+		if err := proof.VerifyInclusion(rfc6962.DefaultHasher, index, size, rfc6962.DefaultHasher.HashLeaf(entry), p, root); err == nil {
+			t.Errorf("VerifyInclusion(%d): accepted the default leaf hash", index)
+		}
+	}
+}
+
+func TestTreeSnapshotRestore(t *testing.T) {
+	for _, size := range []uint64{0, 1, 7, 8, 100, 257} {
+		t.Run(fmt.Sprintf("size:%d", size), func(t *testing.T) {
+			mt1 := newTree(genEntries(size))
+			mt2 := newTree(nil)
+			if err := mt2.Restore(mt1.Snapshot()); err != nil {
+				t.Fatalf("Restore: %v", err)
+			}
+			if diff := cmp.Diff(mt1, mt2, cmp.AllowUnexported(Tree{}, memStore{}), cmpopts.EquateEmpty()); diff != "" {
+				t.Fatalf("Restored tree mismatch: diff (-mt1 +mt2)\n%s", diff)
+			}
+			for i := uint64(0); i < size; i++ {
+				p1, err := mt1.InclusionProof(i, size)
+				if err != nil {
+					t.Fatalf("InclusionProof: %v", err)
+				}
+				p2, err := mt2.InclusionProof(i, size)
+				if err != nil {
+					t.Fatalf("InclusionProof: %v", err)
+				}
+				if diff := cmp.Diff(p1, p2); diff != "" {
+					t.Fatalf("InclusionProof(%d): diff (-mt1 +mt2)\n%s", i, diff)
+				}
+			}
+
+			// Both trees must continue growing identically.
+			mt1.AppendData([]byte("more"))
+			mt2.AppendData([]byte("more"))
+			if got, want := mt2.Hash(), mt1.Hash(); !bytes.Equal(got, want) {
+				t.Errorf("Hash after append: %x, want %x", got, want)
+			}
+		})
+	}
+}
+
+func TestTreeRestoreErrors(t *testing.T) {
+	snapshot := newTree(genEntries(10)).Snapshot()
+	for _, tc := range []struct {
+		desc string
+		data []byte
+	}{
+		{desc: "empty", data: nil},
+		{desc: "no-hashes", data: snapshot[:8]},
+		{desc: "truncated", data: snapshot[:len(snapshot)-1]},
+		{desc: "trailing", data: append(append([]byte{}, snapshot...), 0)},
+		// The node count for this size overflows to the number of hashes.
+		{desc: "huge-size", data: append(binary.BigEndian.AppendUint64(nil, 1<<63+11), snapshot[8:]...)},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := newTree(nil).Restore(tc.data); err == nil {
+				t.Error("Restore succeeded unexpectedly")
+			}
+		})
+	}
+	if err := newTree(genEntries(3)).Restore(snapshot); err == nil {
+		t.Error("Restore into a non-empty tree succeeded unexpectedly")
+	}
+}
+
+// countingStore is a NodeStore that counts reads and writes.
+type countingStore struct {
+	nodes         map[compact.NodeID][]byte
+	reads, writes int
+}
+
+func (c *countingStore) Get(id compact.NodeID) []byte {
+	c.reads++
+	return c.nodes[id]
+}
+
+func (c *countingStore) Set(id compact.NodeID, hash []byte) {
+	c.writes++
+	c.nodes[id] = hash
+}
+
+func TestTreeWithStore(t *testing.T) {
+	const size = 100
+	store := &countingStore{nodes: make(map[compact.NodeID][]byte)}
+	mt := NewWithStore(rfc6962.DefaultHasher, store)
+	mt.AppendData(genEntries(size)...)
+	ref := newTree(genEntries(size))
+
+	// Each perfect subtree is written once, and each internal node takes one
+	// read of its left child.
+	var nodes int
+	for level := uint(0); size>>level != 0; level++ {
+		nodes += size >> level
+	}
+	if got, want := store.writes, nodes; got != want {
+		t.Errorf("writes: got %d, want %d", got, want)
+	}
+	if got, want := store.reads, nodes-size; got != want {
+		t.Errorf("reads: got %d, want %d", got, want)
+	}
+	if got, want := len(store.nodes), nodes; got != want {
+		t.Errorf("stored nodes: got %d, want %d", got, want)
+	}
+
+	if got, want := mt.Hash(), ref.Hash(); !bytes.Equal(got, want) {
+		t.Errorf("Hash: got %x, want %x", got, want)
+	}
+	store.reads = 0
+	p, err := mt.InclusionProof(13, size)
+	if err != nil {
+		t.Fatalf("InclusionProof: %v", err)
+	}
+	want, err := ref.InclusionProof(13, size)
+	if err != nil {
+		t.Fatalf("InclusionProof: %v", err)
+	}
+	if diff := cmp.Diff(want, p); diff != "" {
+		t.Errorf("InclusionProof: diff (-want +got)\n%s", diff)
+	}
+	if store.reads == 0 {
+		t.Error("InclusionProof did not read from the store")
+	}
+	if got, want := mt.Snapshot(), ref.Snapshot(); !bytes.Equal(got, want) {
+		t.Error("Snapshot mismatch")
+	}
+}
+
 func newTree(entries [][]byte) *Tree {
 	tree := New(rfc6962.DefaultHasher)
 	tree.AppendData(entries...)
 	return tree
 }
 
-// genEntries a slice of entries of the given size.
+// genEntries returns a slice of entries of the given size.
 func genEntries(size uint64) [][]byte {
-	entries := make([][]byte, size)
-	for i := range entries {
-		entries[i] = []byte(strconv.Itoa(i))
-	}
-	return entries
+	return randomEntries(0, size)
 }
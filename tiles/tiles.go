@@ -0,0 +1,53 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package tiles contains helpers for logs that store Merkle tree hashes in
+// tiles, as described in https://c2sp.org/tlog-tiles.
+package tiles
+
+// TileKey identifies a tile of a Merkle tree.
+//
+// A tile of height H at level L and index N stores the tree nodes at levels
+// L*H through L*H+H-1. Its bottom row consists of the tree nodes at level L*H
+// with indices in [N*2^H, N*2^H+Width). A tile is full if its Width is 2^H, and
+// partial otherwise. Partial tiles only occur at the right edge of the tree.
+type TileKey struct {
+	Level uint
+	Index uint64
+	Width uint64
+}
+
+// TilesForSize returns the keys of all the tiles of the given height that
+// exist in a tree of the given size: the full tiles, and the partial tile at
+// the right edge of each tile level, if any. The tiles are ordered by level,
+// and then by index within the level.
+//
+// Panics if tileHeight is 0 or greater than 63.
+func TilesForSize(size uint64, tileHeight uint) []TileKey {
+	if tileHeight == 0 || tileHeight > 63 {
+		panic("tiles: tileHeight out of range")
+	}
+	mask := uint64(1)<<tileHeight - 1
+	var keys []TileKey
+	for level, count := uint(0), size; count != 0; level, count = level+1, count>>tileHeight {
+		full := count >> tileHeight
+		for index := uint64(0); index < full; index++ {
+			keys = append(keys, TileKey{Level: level, Index: index, Width: mask + 1})
+		}
+		if width := count & mask; width != 0 {
+			keys = append(keys, TileKey{Level: level, Index: full, Width: width})
+		}
+	}
+	return keys
+}
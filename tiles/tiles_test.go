@@ -0,0 +1,89 @@
+// Copyright 2026 Google LLC. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tiles
+
+import (
+	cmpstd "cmp"
+	"slices"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/mod/sumdb/tlog"
+)
+
+func TestTilesForSize(t *testing.T) {
+	for _, tc := range []struct {
+		size   uint64
+		height uint
+		want   []TileKey
+	}{
+		{size: 0, height: 8, want: nil},
+		{size: 1, height: 8, want: []TileKey{{Level: 0, Index: 0, Width: 1}}},
+		{size: 256, height: 8, want: []TileKey{
+			{Level: 0, Index: 0, Width: 256},
+			{Level: 1, Index: 0, Width: 1},
+		}},
+		{size: 1000, height: 8, want: []TileKey{
+			{Level: 0, Index: 0, Width: 256},
+			{Level: 0, Index: 1, Width: 256},
+			{Level: 0, Index: 2, Width: 256},
+			{Level: 0, Index: 3, Width: 232},
+			{Level: 1, Index: 0, Width: 3},
+		}},
+		{size: 7, height: 1, want: []TileKey{
+			{Level: 0, Index: 0, Width: 2},
+			{Level: 0, Index: 1, Width: 2},
+			{Level: 0, Index: 2, Width: 2},
+			{Level: 0, Index: 3, Width: 1},
+			{Level: 1, Index: 0, Width: 2},
+			{Level: 1, Index: 1, Width: 1},
+			{Level: 2, Index: 0, Width: 1},
+		}},
+	} {
+		got := TilesForSize(tc.size, tc.height)
+		if diff := cmp.Diff(tc.want, got); diff != "" {
+			t.Errorf("TilesForSize(%d, %d): diff (-want +got)\n%s", tc.size, tc.height, diff)
+		}
+	}
+}
+
+// TestTilesForSizeSumDB checks that the tile set matches the tiles that the Go
+// checksum database would store for the same tree.
+func TestTilesForSizeSumDB(t *testing.T) {
+	for _, height := range []uint{1, 2, 3, 8} {
+		for size := uint64(0); size <= 600; size++ {
+			// tlog.NewTiles returns the tiles that change when growing the tree,
+			// which are all tiles when growing from the empty tree.
+			var want []TileKey
+			for _, tile := range tlog.NewTiles(int(height), 0, int64(size)) {
+				want = append(want, TileKey{Level: uint(tile.L), Index: uint64(tile.N), Width: uint64(tile.W)})
+			}
+			got := TilesForSize(size, height)
+			sortKeys(want)
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatalf("TilesForSize(%d, %d): diff (-want +got)\n%s", size, height, diff)
+			}
+		}
+	}
+}
+
+func sortKeys(keys []TileKey) {
+	slices.SortFunc(keys, func(a, b TileKey) int {
+		if a.Level != b.Level {
+			return cmpstd.Compare(a.Level, b.Level)
+		}
+		return cmpstd.Compare(a.Index, b.Index)
+	})
+}